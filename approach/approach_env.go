@@ -6,17 +6,20 @@ package main
 
 import (
 	"fmt"
-	"math/rand"
+	"math"
 
 	"github.com/emer/emergent/env"
 	"github.com/emer/emergent/erand"
 	"github.com/emer/emergent/evec"
-	"github.com/emer/emergent/patgen"
+	"github.com/emer/emergent/popcode"
 	"github.com/emer/etable/etensor"
+	"github.com/emer/etable/metric"
+	"github.com/goki/mat32"
 )
 
 // Approach implements CS-guided approach to desired outcomes.
-// Each location contains a US which satisfies a different drive.
+// Each location contains a US which satisfies a different drive,
+// or a neutral or aversive distractor outcome.
 type Approach struct {
 
 	// name of environment -- Train or Test
@@ -28,8 +31,23 @@ type Approach struct {
 	// number of different CS sensory cues associated with each US (simplest case is 1 -- one-to-one mapping), presented on a fovea input layer
 	CSPerDrive int `desc:"number of different CS sensory cues associated with each US (simplest case is 1 -- one-to-one mapping), presented on a fovea input layer"`
 
-	// number of different locations -- always <= number of drives -- drives have a unique location
-	Locations int `desc:"number of different locations -- always <= number of drives -- drives have a unique location"`
+	// number of different locations with drive USs -- always <= number of drives -- drives have a unique location
+	Locations int `desc:"number of different locations with drive USs -- always <= number of drives -- drives have a unique location"`
+
+	// number of additional distractor locations with a neutral outcome, which neither satisfies a drive nor gives reward
+	Neutrals int `desc:"number of additional distractor locations with a neutral outcome, which neither satisfies a drive nor gives reward"`
+
+	// number of additional distractor locations with an aversive outcome, which gives negative reward of AversiveVal
+	Aversives int `desc:"number of additional distractor locations with an aversive outcome, which gives negative reward of AversiveVal"`
+
+	// negative reward for consuming an aversive outcome, times its magnitude
+	AversiveVal float32 `desc:"negative reward for consuming an aversive outcome, times its magnitude"`
+
+	// total number of locations = Locations + Neutrals + Aversives
+	LocTot int `inactive:"+" desc:"total number of locations = Locations + Neutrals + Aversives"`
+
+	// effort cost of each action, subtracted from the reward when it is taken -- actions not in the map are free
+	ActCosts map[string]float32 `desc:"effort cost of each action, subtracted from the reward when it is taken -- actions not in the map are free"`
 
 	// maximum distance in time steps to reach the US
 	DistMax int `desc:"maximum distance in time steps to reach the US"`
@@ -40,8 +58,11 @@ type Approach struct {
 	// interval in trials for generating a new state, only if > 0
 	NewStateInt int `desc:"interval in trials for generating a new state, only if > 0"`
 
-	// total number of CS's = Drives * CSPerDrive
-	CSTot int `desc:"total number of CS's = Drives * CSPerDrive"`
+	// total number of CS's = Drives * CSPerDrive, plus CSPerDrive for each type of distractor present
+	CSTot int `desc:"total number of CS's = Drives * CSPerDrive, plus CSPerDrive for each type of distractor present"`
+
+	// render the Dist as a population code instead of localist, for longer DistMax ranges
+	DistPop bool `desc:"render the Dist as a population code instead of localist, for longer DistMax ranges"`
 
 	// number of Y-axis repetitions of localist stimuli -- for redundancy in spiking nets
 	NYReps int `desc:"number of Y-axis repetitions of localist stimuli -- for redundancy in spiking nets"`
@@ -49,6 +70,42 @@ type Approach struct {
 	// size of CS patterns
 	PatSize evec.Vec2i `desc:"size of CS patterns"`
 
+	// number of bits on in each CS pattern
+	PatOn int `desc:"number of bits on in each CS pattern"`
+
+	// minimum number of bits that differ between any two CS patterns
+	PatMinDiff int `desc:"minimum number of bits that differ between any two CS patterns"`
+
+	// amount that each drive level rises per time step, toward a maximum of 1
+	DriveRise float32 `desc:"amount that each drive level rises per time step, toward a maximum of 1"`
+
+	// amount that a drive level falls when its US is consumed, toward a minimum of 0
+	Satiation float32 `desc:"amount that a drive level falls when its US is consumed, toward a minimum of 0"`
+
+	// [view: no-inline] population code for rendering the drive levels, and Dist if DistPop
+	PopCode popcode.OneD `view:"no-inline" desc:"population code for rendering the drive levels, and Dist if DistPop"`
+
+	// number of units in the population codes
+	PopSize int `desc:"number of units in the population codes"`
+
+	// probability that the US is delivered when consumed at each location -- otherwise it is omitted -- defaults to 1 for all locations
+	LocProbs []float32 `desc:"probability that the US is delivered when consumed at each location -- otherwise it is omitted -- defaults to 1 for all locations"`
+
+	// delay in time steps between Consume and delivery of the US at each location, during which actions have no effect -- defaults to 0 for all locations
+	LocDelays []int `desc:"delay in time steps between Consume and delivery of the US at each location, during which actions have no effect -- defaults to 0 for all locations"`
+
+	// mean magnitude of the US at each location, which multiplies the satiation and reward (or the AversiveVal) -- defaults to 1 for all locations
+	LocMags []float32 `desc:"mean magnitude of the US at each location, which multiplies the satiation and reward (or the AversiveVal) -- defaults to 1 for all locations"`
+
+	// standard deviation of the US magnitude, as a proportion of the mean for the location
+	MagSD float32 `desc:"standard deviation of the US magnitude, as a proportion of the mean for the location"`
+
+	// random seed for the Rand random number generator, used for all random draws -- set at Init
+	RndSeed int64 `desc:"random seed for the Rand random number generator, used for all random draws -- set at Init"`
+
+	// [view: -] random number generator for the env, seeded from RndSeed
+	Rand erand.SysRand `view:"-" desc:"random number generator for the env, seeded from RndSeed"`
+
 	// list of actions
 	Acts []string `desc:"list of actions"`
 
@@ -58,11 +115,14 @@ type Approach struct {
 	// named states -- e.g., USs, CSs, etc
 	States map[string]*etensor.Float32 `desc:"named states -- e.g., USs, CSs, etc"`
 
-	// target position where Drive US is
-	TrgPos int `desc:"target position where Drive US is"`
+	// target position where Drive US is -- -1 if none
+	TrgPos int `desc:"target position where Drive US is -- -1 if none"`
+
+	// current target drive, with the highest level among those whose US is in one of the locations -- -1 if none
+	Drive int `desc:"current target drive, with the highest level among those whose US is in one of the locations -- -1 if none"`
 
-	// current drive state
-	Drive int `desc:"current drive state"`
+	// current level of each drive (0-1), rising over time and falling with consumption of its US
+	DriveLevels []float32 `desc:"current level of each drive (0-1), rising over time and falling with consumption of its US"`
 
 	// current distance
 	Dist int `desc:"current distance"`
@@ -73,17 +133,117 @@ type Approach struct {
 	// current position being looked at
 	Pos int `desc:"current position being looked at"`
 
-	// reward
-	Rew float32 `desc:"reward"`
+	// reward: the level of the drive whose US was delivered, prior to consumption, times the US magnitude
+	Rew float32 `desc:"reward: the level of the drive whose US was delivered, prior to consumption, times the US magnitude"`
 
-	// US is -1 unless consumed at Dist = 0
-	US int `desc:"US is -1 unless consumed at Dist = 0"`
+	// US is -1 unless delivered after being consumed at Dist = 0
+	US int `desc:"US is -1 unless delivered after being consumed at Dist = 0"`
+
+	// US consumed and pending delivery after a delay, -1 if none
+	PendUS int `desc:"US consumed and pending delivery after a delay, -1 if none"`
+
+	// number of time steps until the PendUS is delivered
+	PendTime int `desc:"number of time steps until the PendUS is delivered"`
+
+	// magnitude of the PendUS when delivered -- 0 if it is omitted
+	PendMag float32 `desc:"magnitude of the PendUS when delivered -- 0 if it is omitted"`
 
 	// count up for generating a new state
 	StateCtr int `desc:"count up for generating a new state"`
 
+	// [view: inline] schedule for remapping the USs and CSs in locations, for reversal learning
+	Remap RemapSched `view:"inline" desc:"schedule for remapping the USs and CSs in locations, for reversal learning"`
+
+	// US predicted by each CS pattern, changed by RemapCS and RemapReverse
+	CSToUS []int `desc:"US predicted by each CS pattern, changed by RemapCS and RemapReverse"`
+
+	// number of trials completed since the last remapping
+	RemapCtr int `inactive:"+" desc:"number of trials completed since the last remapping"`
+
+	// number of consecutive correct approaches: consuming the target US without any wrong consumes
+	Consec int `inactive:"+" desc:"number of consecutive correct approaches: consuming the target US without any wrong consumes"`
+
+	// log of all remappings since Init
+	Remaps []RemapEvent `desc:"log of all remappings since Init"`
+
 	// last action taken
 	LastAct int `desc:"last action taken"`
+
+	// use the OracleAct optimal policy in ActGen, instead of the heuristic
+	Oracle bool `desc:"use the OracleAct optimal policy in ActGen, instead of the heuristic"`
+
+	// outcome of the current trial so far
+	Outcome TrialOutcome `inactive:"+" desc:"outcome of the current trial so far"`
+
+	// outcome of the previous trial -- available when the Trial counter has changed
+	PrvOutcome TrialOutcome `inactive:"+" desc:"outcome of the previous trial -- available when the Trial counter has changed"`
+
+	// outcomes of all completed trials since Init
+	Outcomes []TrialOutcome `view:"-" desc:"outcomes of all completed trials since Init"`
+
+	// [view: inline] current run of model as provided during Init
+	Run env.Ctr `view:"inline" desc:"current run of model as provided during Init"`
+
+	// [view: inline] increments over Trial.Max trials, for general stats-tracking
+	Epoch env.Ctr `view:"inline" desc:"increments over Trial.Max trials, for general stats-tracking"`
+
+	// [view: inline] one approach trial, from NewStart until consumption or TimeMax
+	Trial env.Ctr `view:"inline" desc:"one approach trial, from NewStart until consumption or TimeMax"`
+
+	// [view: inline] steps within the current trial -- same as Time
+	Tick env.Ctr `view:"inline" desc:"steps within the current trial -- same as Time"`
+
+	// [view: inline] monolithic counter of all steps, not reset at trial boundaries
+	Event env.Ctr `view:"inline" desc:"monolithic counter of all steps, not reset at trial boundaries"`
+
+	// [view: inline] trials within the current episode
+	Scene env.Ctr `view:"inline" desc:"trials within the current episode"`
+
+	// [view: inline] one configuration of USs in locations, generated by NewState
+	Episode env.Ctr `view:"inline" desc:"one configuration of USs in locations, generated by NewState"`
+}
+
+// TrialOutcome records the outcome of one approach trial
+type TrialOutcome struct {
+
+	// Trial counter value for this trial
+	Trial int `desc:"Trial counter value for this trial"`
+
+	// drive that was to be satisfied -- -1 if none
+	Drive int `desc:"drive that was to be satisfied -- -1 if none"`
+
+	// true if the US for the Drive was consumed
+	Success bool `desc:"true if the US for the Drive was consumed"`
+
+	// number of steps taken in the trial
+	Time int `desc:"number of steps taken in the trial"`
+
+	// number of Consume actions that did not deliver the US for the Drive: at a distance, or consuming a different US
+	WrongConsumes int `desc:"number of Consume actions that did not deliver the US for the Drive: at a distance, or consuming a different US"`
+
+	// US that was consumed, -1 if none
+	US int `desc:"US that was consumed, -1 if none"`
+
+	// true if the consumed US was omitted, according to the LocProbs
+	Omitted bool `desc:"true if the consumed US was omitted, according to the LocProbs"`
+
+	// reward received when the US was delivered
+	Rew float32 `desc:"reward received when the US was delivered"`
+
+	// total effort cost of the actions taken in the trial
+	Effort float32 `desc:"total effort cost of the actions taken in the trial"`
+
+	// actual return for the trial: Rew - Effort
+	Return float32 `desc:"actual return for the trial: Rew - Effort"`
+
+	// minimum number of steps to consume the target US and have it delivered, from the start of the trial -- -1 if not possible before TimeMax
+	OptSteps int `desc:"minimum number of steps to consume the target US and have it delivered, from the start of the trial -- -1 if not possible before TimeMax"`
+
+	// expected return for the shortest path to the target US from the start of the trial, under the current cost settings
+	OptReturn float32 `desc:"expected return for the shortest path to the target US from the start of the trial, under the current cost settings"`
+
+	// efficiency of the trial: OptSteps / Time if successful, else 0 -- 1 = optimal
+	Efficiency float32 `desc:"efficiency of the trial: OptSteps / Time if successful, else 0 -- 1 = optimal"`
 }
 
 func (ev *Approach) Name() string {
@@ -100,102 +260,366 @@ func (ev *Approach) Defaults() {
 	ev.Drives = 4
 	ev.CSPerDrive = 1
 	ev.Locations = 4 // <= drives always
+	ev.Neutrals = 0
+	ev.Aversives = 0
+	ev.AversiveVal = 1
+	ev.ActCosts = map[string]float32{"Forward": 0, "Left": 0, "Right": 0, "Consume": 0}
+	ev.DistPop = false
+	ev.Oracle = false
 	ev.DistMax = 4
 	ev.TimeMax = 10
 	ev.NewStateInt = -1
 	ev.NYReps = 4
 	ev.PatSize.Set(6, 6)
-	// ev.PopCode.Defaults()
-	// ev.PopCode.SetRange(-0.2, 1.2, 0.1)
+	ev.PatOn = 6
+	ev.PatMinDiff = 3
+	ev.DriveRise = 0.01
+	ev.Satiation = 1
+	ev.PopSize = 12
+	ev.PopCode.Defaults()
+	ev.PopCode.SetRange(-0.2, 1.2, 0.1)
+	ev.LocProbs = nil
+	ev.LocDelays = nil
+	ev.LocMags = nil
+	ev.MagSD = 0
+	ev.RndSeed = 1
+	ev.Trial.Max = 100
 }
 
 // Config configures the world
 func (ev *Approach) Config() {
-	ev.CSTot = ev.Drives * ev.CSPerDrive
+	ev.LocTot = ev.Locations + ev.Neutrals + ev.Aversives
+	ev.CSTot = len(ev.USTypes()) * ev.CSPerDrive
+	nus := ev.Drives + 1 // last = no US
+	if ev.Neutrals+ev.Aversives > 0 {
+		nus += 2
+	}
 	ev.ActMap = make(map[string]int)
 	for i, act := range ev.Acts {
 		ev.ActMap[act] = i
 	}
 	ev.States = make(map[string]*etensor.Float32)
-	ev.States["USs"] = etensor.NewFloat32([]int{ev.Locations}, nil, nil)
-	ev.States["CSs"] = etensor.NewFloat32([]int{ev.Locations}, nil, nil)
-	ev.States["Pos"] = etensor.NewFloat32([]int{ev.NYReps, ev.Locations}, nil, nil)
-	ev.States["Drives"] = etensor.NewFloat32([]int{ev.NYReps, ev.Drives}, nil, nil)
-	ev.States["US"] = etensor.NewFloat32([]int{ev.NYReps, ev.Drives + 1}, nil, nil)
+	ev.States["USs"] = etensor.NewFloat32([]int{ev.LocTot}, nil, nil)
+	ev.States["CSs"] = etensor.NewFloat32([]int{ev.LocTot}, nil, nil)
+	ev.States["Pos"] = etensor.NewFloat32([]int{ev.NYReps, ev.LocTot}, nil, nil)
+	ev.States["Drives"] = etensor.NewFloat32([]int{ev.Drives, ev.PopSize}, nil, []string{"Drive", "Pop"})
+	ev.States["US"] = etensor.NewFloat32([]int{ev.NYReps, nus}, nil, nil)
 	ev.States["CS"] = etensor.NewFloat32([]int{ev.PatSize.Y, ev.PatSize.X}, nil, nil)
-	ev.States["Dist"] = etensor.NewFloat32([]int{ev.NYReps, ev.DistMax}, nil, nil)
+	if ev.DistPop {
+		ev.States["Dist"] = etensor.NewFloat32([]int{ev.NYReps, ev.PopSize}, nil, nil)
+	} else {
+		ev.States["Dist"] = etensor.NewFloat32([]int{ev.NYReps, ev.DistMax}, nil, nil)
+	}
 	ev.States["Time"] = etensor.NewFloat32([]int{ev.NYReps, ev.TimeMax}, nil, nil)
 	ev.States["Rew"] = etensor.NewFloat32([]int{1, 1}, nil, nil)
 	ev.States["Action"] = etensor.NewFloat32([]int{1, len(ev.Acts)}, nil, nil)
 
-	ev.ConfigPats()
+	ev.ConfigOutcomes()
+	if err := ev.ConfigPats(); err != nil {
+		fmt.Println(err) // also returned by Validate
+	}
+	ev.InitCSMap()
+	ev.InitDrives()
 	ev.NewState()
 }
 
-// ConfigPats generates patterns for CS's
-func (ev *Approach) ConfigPats() {
+// ConfigOutcomes sets the per-location outcome parameters to their
+// deterministic defaults, for any that are not set for all locations
+func (ev *Approach) ConfigOutcomes() {
+	if len(ev.LocProbs) != ev.LocTot {
+		ev.LocProbs = make([]float32, ev.LocTot)
+		for i := range ev.LocProbs {
+			ev.LocProbs[i] = 1
+		}
+	}
+	if len(ev.LocDelays) != ev.LocTot {
+		ev.LocDelays = make([]int, ev.LocTot)
+	}
+	if len(ev.LocMags) != ev.LocTot {
+		ev.LocMags = make([]float32, ev.LocTot)
+		for i := range ev.LocMags {
+			ev.LocMags[i] = 1
+		}
+	}
+}
+
+// ConfigPats generates patterns for CS's, with PatOn bits on and at least
+// PatMinDiff bits different from each other, as in patgen.PermutedBinaryMinDiff
+// but using Rand.  Returns an error if some patterns are still too similar
+// after 100 iterations.
+func (ev *Approach) ConfigPats() error {
 	pats := etensor.NewFloat32([]int{ev.CSTot, ev.PatSize.Y, ev.PatSize.X}, nil, nil)
-	patgen.PermutedBinaryMinDiff(pats, 6, 1, 0, 3)
 	ev.States["Pats"] = pats
+	sz := ev.PatSize.X * ev.PatSize.Y
+	if ev.PatOn > sz {
+		return fmt.Errorf("Approach: %v PatOn: %d is more than the PatSize: %d", ev.Nm, ev.PatOn, sz)
+	}
+	nunder := make([]int, ev.CSTot) // per pattern
+	for itr := 0; itr < 100; itr++ {
+		for p := 0; p < ev.CSTot; p++ {
+			if itr > 0 && nunder[p] == 0 {
+				continue
+			}
+			pat := pats.SubSpace([]int{p}).(*etensor.Float32)
+			pat.SetZeros()
+			pord := ev.Rand.Perm(sz, -1)
+			for i := 0; i < ev.PatOn; i++ {
+				pat.Values[pord[i]] = 1
+			}
+		}
+		if ev.PatDiffFails(pats, nunder) == 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("Approach: %v could not generate %d CS patterns with PatOn: %d at least PatMinDiff: %d apart", ev.Nm, ev.CSTot, ev.PatOn, ev.PatMinDiff)
+}
+
+// PatDiffFails returns the number of patterns that are less than
+// PatMinDiff bits different from an earlier pattern, recording in
+// nunder the number of such earlier patterns for each pattern
+func (ev *Approach) PatDiffFails(pats *etensor.Float32, nunder []int) int {
+	fails := 0
+	for p := 0; p < ev.CSTot; p++ {
+		nunder[p] = 0
+		pat := pats.SubSpace([]int{p}).(*etensor.Float32)
+		for op := 0; op < p; op++ {
+			opat := pats.SubSpace([]int{op}).(*etensor.Float32)
+			if int(math.Round(float64(.5*metric.Hamming32(pat.Values, opat.Values)))) < ev.PatMinDiff {
+				nunder[p]++
+			}
+		}
+		if nunder[p] > 0 {
+			fails++
+		}
+	}
+	return fails
 }
 
 func (ev *Approach) Validate() error {
+	if ev.DistMax < 2 {
+		return fmt.Errorf("Approach: %v DistMax: %d must be at least 2", ev.Nm, ev.DistMax)
+	}
+	if ev.Locations < 1 || ev.Locations > ev.Drives {
+		return fmt.Errorf("Approach: %v Locations: %d must be between 1 and Drives: %d, so there is a drive US to approach", ev.Nm, ev.Locations, ev.Drives)
+	}
+	if sz := ev.PatSize.X * ev.PatSize.Y; ev.PatOn > sz {
+		return fmt.Errorf("Approach: %v PatOn: %d is more than the PatSize: %d", ev.Nm, ev.PatOn, sz)
+	}
+	if pats, ok := ev.States["Pats"]; ok && ev.PatDiffFails(pats, make([]int, ev.CSTot)) > 0 {
+		return fmt.Errorf("Approach: %v CS patterns are not all at least PatMinDiff: %d apart -- reduce PatMinDiff or increase PatSize", ev.Nm, ev.PatMinDiff)
+	}
 	return nil
 }
 
 func (ev *Approach) Init(run int) {
+	ev.Run.Scale = env.Run
+	ev.Epoch.Scale = env.Epoch
+	ev.Trial.Scale = env.Trial
+	ev.Tick.Scale = env.Tick
+	ev.Event.Scale = env.Event
+	ev.Scene.Scale = env.Scene
+	ev.Episode.Scale = env.Episode
+
+	ev.Run.Init()
+	ev.Epoch.Init()
+	ev.Trial.Init()
+	ev.Tick.Init()
+	ev.Event.Init()
+	ev.Scene.Init()
+	ev.Episode.Init()
+
+	ev.Run.Cur = run
+	ev.Trial.Cur = -1 // init state -- key so that first NewStart = 0
+	ev.Event.Cur = -1
+	ev.Episode.Cur = -1
+	ev.Outcomes = nil
+	ev.Remaps = nil
+	ev.RemapCtr = 0
+	ev.Consec = 0
+	ev.Rand.NewRand(ev.RndSeed)
 	ev.Config()
 }
 
 func (ev *Approach) Counter(scale env.TimeScales) (cur, prv int, changed bool) {
-	return 0, 0, false
+	switch scale {
+	case env.Run:
+		return ev.Run.Query()
+	case env.Epoch:
+		return ev.Epoch.Query()
+	case env.Trial:
+		return ev.Trial.Query()
+	case env.Tick:
+		return ev.Tick.Query()
+	case env.Event:
+		return ev.Event.Query()
+	case env.Scene:
+		return ev.Scene.Query()
+	case env.Episode:
+		return ev.Episode.Query()
+	}
+	return -1, -1, false
 }
 
 func (ev *Approach) State(el string) etensor.Tensor {
 	return ev.States[el]
 }
 
-// NewState configures new set of USs in locations
+// NewState configures new set of USs in locations, and starts a new trial
 func (ev *Approach) NewState() {
+	ev.GenState()
+	ev.NewStart()
+}
+
+// GenState generates a new set of USs in locations, starting a new Episode.
+// The drive USs and distractors are in random locations.
+func (ev *Approach) GenState() {
 	uss := ev.States["USs"]
 	css := ev.States["CSs"]
-	drives := rand.Perm(ev.Drives)
-	for l := 0; l < ev.Locations; l++ {
-		us := drives[l]
-		cs := rand.Intn(ev.CSPerDrive)
-		pat := us*ev.CSPerDrive + cs
+	drives := ev.Rand.Perm(ev.Drives, -1)
+	for i, l := range ev.Rand.Perm(ev.LocTot, -1) {
+		us := ev.AversiveUS()
+		switch {
+		case i < ev.Locations:
+			us = drives[i]
+		case i < ev.Locations+ev.Neutrals:
+			us = ev.NeutralUS()
+		}
 		uss.Values[l] = float32(us)
-		css.Values[l] = float32(pat)
+		css.Values[l] = float32(ev.CSForUS(us))
 	}
 	ev.StateCtr = 0
-	ev.NewStart()
+	ev.Episode.Incr()
+	ev.Scene.Set(-1) // next NewStart = 0
+}
+
+// NeutralUS returns the US number for the neutral distractor outcome
+func (ev *Approach) NeutralUS() int {
+	return ev.Drives + 1
+}
+
+// AversiveUS returns the US number for the aversive distractor outcome
+func (ev *Approach) AversiveUS() int {
+	return ev.Drives + 2
+}
+
+// USTypes returns the US numbers that are present in locations, and have
+// their own CSs: the drive USs, followed by the distractors that are present
+func (ev *Approach) USTypes() []int {
+	ust := make([]int, ev.Drives, ev.Drives+2)
+	for i := range ust {
+		ust[i] = i
+	}
+	if ev.Neutrals > 0 {
+		ust = append(ust, ev.NeutralUS())
+	}
+	if ev.Aversives > 0 {
+		ust = append(ust, ev.AversiveUS())
+	}
+	return ust
+}
+
+// InitDrives initializes the drive levels to random values
+func (ev *Approach) InitDrives() {
+	ev.DriveLevels = make([]float32, ev.Drives)
+	for i := range ev.DriveLevels {
+		ev.DriveLevels[i] = ev.Rand.Float32(-1)
+	}
+}
+
+// RiseDrives increases the drive levels by DriveRise for one time step
+func (ev *Approach) RiseDrives() {
+	for i, lv := range ev.DriveLevels {
+		ev.DriveLevels[i] = mat32.Min(lv+ev.DriveRise, 1)
+	}
+}
+
+// SatiateDrive decreases the level of given drive by Satiation times given
+// magnitude of the US, returning the level prior to consumption, which is
+// the deficit that was satisfied
+func (ev *Approach) SatiateDrive(drive int, mag float32) float32 {
+	lv := ev.DriveLevels[drive]
+	ev.DriveLevels[drive] = mat32.Max(lv-mag*ev.Satiation, 0)
+	return lv
+}
+
+// ChooseDrive chooses the target drive with the highest current level among
+// those whose US is in one of the locations (ties are broken at random),
+// returning the location of its US -- if no location has a drive US,
+// the Drive and returned location are -1
+func (ev *Approach) ChooseDrive() int {
+	uss := ev.States["USs"]
+	trg := -1
+	mx := float32(-1)
+	for _, l := range ev.Rand.Perm(ev.LocTot, -1) {
+		us := int(uss.Values[l])
+		if us >= ev.Drives {
+			continue
+		}
+		lv := ev.DriveLevels[us]
+		if lv > mx {
+			mx = lv
+			trg = l
+		}
+	}
+	ev.Drive = -1
+	if trg >= 0 {
+		ev.Drive = int(uss.Values[trg])
+	}
+	return trg
 }
 
 // PatToUS returns US no and CS no from pat no
 func (ev *Approach) PatToUS(pat int) (us, cs int) {
-	us = pat / ev.CSPerDrive
+	us = ev.CSToUS[pat]
 	cs = pat % ev.CSPerDrive
 	return
 }
 
-// NewStart starts a new approach run
+// NewStart starts a new approach trial
 func (ev *Approach) NewStart() {
+	if ev.Trial.Incr() { // true if wraps around Max back to 0
+		ev.Epoch.Incr()
+	}
 	ev.StateCtr++
 	if ev.NewStateInt > 0 && ev.StateCtr >= ev.NewStateInt {
-		ev.NewState()
+		ev.DoRemap(RemapNew)
 	}
-	ev.Dist = 1 + rand.Intn(ev.DistMax-1)
+	ev.CheckRemap()
+	ev.Scene.Incr()
+	ev.Tick.Set(-1) // next Step = 0
+	ev.Dist = 1 + ev.Rand.Intn(ev.DistMax-1, -1)
 	ev.Time = 0
-	ev.Pos = rand.Intn(ev.Locations)
-	ev.TrgPos = rand.Intn(ev.Locations)
-	uss := ev.States["USs"]
-	ev.Drive = int(uss.Values[ev.TrgPos])
+	ev.Pos = ev.Rand.Intn(ev.LocTot, -1)
+	ev.TrgPos = ev.ChooseDrive()
 	ev.US = -1
+	ev.PendUS = -1
 	ev.Rew = 0
+	ev.Outcome = TrialOutcome{Trial: ev.Trial.Cur, Drive: ev.Drive, US: -1}
+	ev.OracleStart()
 	ev.RenderState()
 	ev.RenderRewUS()
 }
 
+// TrialDone returns true if the current trial is over: the US has been
+// consumed, or TimeMax has been reached, and no US is pending delivery
+func (ev *Approach) TrialDone() bool {
+	return ev.PendUS < 0 && (ev.Dist < 0 || ev.Time >= ev.TimeMax)
+}
+
+// EndTrial records the Outcome of the current trial into PrvOutcome and Outcomes
+func (ev *Approach) EndTrial() {
+	ev.Outcome.Time = ev.Time
+	ev.OracleEnd()
+	ev.PrvOutcome = ev.Outcome
+	ev.Outcomes = append(ev.Outcomes, ev.Outcome)
+	ev.RemapCtr++
+	if ev.Outcome.Success && ev.Outcome.WrongConsumes == 0 {
+		ev.Consec++
+	} else {
+		ev.Consec = 0
+	}
+}
+
 // RenderLocalist renders one localist state
 func (ev *Approach) RenderLocalist(name string, val int) {
 	st := ev.States[name]
@@ -208,9 +632,21 @@ func (ev *Approach) RenderLocalist(name string, val int) {
 // RenderState renders the current state
 func (ev *Approach) RenderState() {
 	ev.RenderLocalist("Pos", ev.Pos)
-	ev.RenderLocalist("Drives", ev.Drive)
-	ev.RenderLocalist("Dist", ev.Dist)
-	ev.RenderLocalist("Time", ev.Time)
+	ev.RenderDrives()
+	if ev.DistPop {
+		ds := ev.States["Dist"]
+		for y := 0; y < ev.NYReps; y++ {
+			sv := ds.SubSpace([]int{y}).(*etensor.Float32)
+			ev.PopCode.Encode(&sv.Values, float32(ev.Dist)/float32(ev.DistMax-1), ev.PopSize, false)
+		}
+	} else {
+		ev.RenderLocalist("Dist", ev.Dist)
+	}
+	tm := ev.Time
+	if tm >= ev.TimeMax { // can go past while waiting for a delayed US
+		tm = ev.TimeMax - 1
+	}
+	ev.RenderLocalist("Time", tm)
 
 	css := ev.States["CSs"]
 	patn := int(css.Values[ev.Pos])
@@ -220,6 +656,15 @@ func (ev *Approach) RenderState() {
 	cs.CopyFrom(pat)
 }
 
+// RenderDrives renders the drive levels as population codes
+func (ev *Approach) RenderDrives() {
+	ds := ev.States["Drives"]
+	for i, lv := range ev.DriveLevels {
+		sv := ds.SubSpace([]int{i}).(*etensor.Float32)
+		ev.PopCode.Encode(&sv.Values, lv, ev.PopSize, false)
+	}
+}
+
 // RenderRewUS renders reward and US
 func (ev *Approach) RenderRewUS() {
 	if ev.US < 0 {
@@ -238,16 +683,27 @@ func (ev *Approach) RenderAction(act int) {
 	as.Values[act] = 1
 }
 
-// Step does one step
+// Step does one step.  If the current trial is over, its outcome is
+// recorded and a new trial is started, and false is returned to signal
+// the trial (episode) boundary.
 func (ev *Approach) Step() bool {
-	if ev.Dist < 0 || ev.Time >= ev.TimeMax {
+	ev.Epoch.Same() // good idea to just reset all non-inner-most counters at start
+	ev.Trial.Same()
+	ev.Scene.Same()
+	ev.Episode.Same()
+	newtrl := false
+	if ev.TrialDone() {
+		ev.EndTrial()
 		ev.NewStart()
+		newtrl = true
 	}
+	ev.Tick.Incr()
+	ev.Event.Incr()
 	ev.RenderState()
 	ev.Rew = 0
 	ev.US = -1
 	ev.RenderRewUS()
-	return true
+	return !newtrl
 }
 
 func (ev *Approach) DecodeAct(vt *etensor.Float32) (int, string) {
@@ -270,36 +726,108 @@ func (ev *Approach) Action(action string, nop etensor.Tensor) {
 	}
 	ev.RenderAction(act)
 	ev.Time++
+	ev.RiseDrives()
+	if cost := ev.ActCosts[action]; cost != 0 {
+		ev.Rew -= cost
+		ev.Outcome.Effort += cost
+	}
+	if ev.PendUS >= 0 { // waiting for delivery: action has no effect
+		ev.PendTime--
+		if ev.PendTime <= 0 {
+			ev.DeliverUS(ev.PendUS, ev.PendMag)
+			ev.PendUS = -1
+			ev.Dist--
+		}
+		ev.LastAct = act
+		ev.RenderRewUS()
+		return
+	}
 	uss := ev.States["USs"]
 	us := int(uss.Values[ev.Pos])
 	switch action {
 	case "Forward":
-		ev.Dist--
+		if ev.Dist > 0 { // no effect once at the US
+			ev.Dist--
+		}
 	case "Left":
 		ev.Pos--
 		if ev.Pos < 0 {
-			ev.Pos += ev.Locations
+			ev.Pos += ev.LocTot
 		}
 	case "Right":
 		ev.Pos++
-		if ev.Pos >= ev.Locations {
-			ev.Pos -= ev.Locations
+		if ev.Pos >= ev.LocTot {
+			ev.Pos -= ev.LocTot
 		}
 	case "Consume":
 		if ev.Dist == 0 {
 			if us == ev.Drive {
-				ev.Rew = 1
+				ev.Outcome.Success = true
+			} else {
+				ev.Outcome.WrongConsumes++
 			}
-			ev.US = us
-			ev.Dist--
+			ev.Outcome.US = us
+			if ev.ConsumeUS(us) {
+				ev.Dist--
+			}
+		} else {
+			ev.Outcome.WrongConsumes++
 		}
 	}
 	ev.LastAct = act
 	ev.RenderRewUS()
 }
 
-// ActGen returns an "instinctive" action that implements a basic policy
+// ConsumeUS consumes given US at the current location, drawing whether
+// it is delivered from LocProbs, and its magnitude from LocMags and MagSD.
+// It is delivered now, returning true, or after the LocDelays for the
+// location, returning false.
+func (ev *Approach) ConsumeUS(us int) bool {
+	mag := float32(0)
+	if erand.BoolP32(ev.LocProbs[ev.Pos], -1, &ev.Rand) {
+		mag = ev.LocMags[ev.Pos]
+		if ev.MagSD > 0 {
+			mag *= 1 + ev.MagSD*float32(ev.Rand.NormFloat64(-1))
+			mag = mat32.Max(mag, 0)
+		}
+	}
+	dl := ev.LocDelays[ev.Pos]
+	if dl <= 0 {
+		ev.DeliverUS(us, mag)
+		return true
+	}
+	ev.PendUS = us
+	ev.PendTime = dl
+	ev.PendMag = mag
+	return false
+}
+
+// DeliverUS delivers given US with given magnitude, satiating its drive and
+// providing reward, or negative reward for an aversive outcome, or nothing
+// for a neutral one -- a magnitude of 0 means it is omitted
+func (ev *Approach) DeliverUS(us int, mag float32) {
+	if mag <= 0 {
+		ev.Outcome.Omitted = true
+		return
+	}
+	ev.US = us
+	rew := float32(0)
+	switch {
+	case us < ev.Drives:
+		rew = mag * ev.SatiateDrive(us, mag)
+	case us == ev.AversiveUS():
+		rew = -mag * ev.AversiveVal
+	}
+	ev.Rew += rew
+	ev.Outcome.Rew = rew
+}
+
+// ActGen returns an "instinctive" action that implements a basic policy,
+// or the OracleAct if Oracle is set
 func (ev *Approach) ActGen() int {
+	if ev.Oracle {
+		return ev.OracleAct()
+	}
 	uss := ev.States["USs"]
 	posUs := int(uss.Values[ev.Pos])
 	if posUs == ev.Drive {
@@ -313,8 +841,11 @@ func (ev *Approach) ActGen() int {
 	if ev.LastAct == lt || ev.LastAct == rt {
 		return ev.LastAct
 	}
-	if erand.BoolP(.5, -1) {
+	if erand.BoolP(.5, -1, &ev.Rand) {
 		return lt
 	}
 	return rt
 }
+
+// Compile-time check that implements Env interface
+var _ env.Env = (*Approach)(nil)
@@ -0,0 +1,460 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/emer/etable/etensor"
+	"github.com/goki/mat32"
+)
+
+// testEnv returns an env with default params, after calling given
+// function (if non-nil) to set params, and Init
+func testEnv(t *testing.T, set func(ev *Approach)) *Approach {
+	ev := &Approach{}
+	ev.Defaults()
+	if set != nil {
+		set(ev)
+	}
+	ev.Init(0)
+	return ev
+}
+
+func TestNoDriveUS(t *testing.T) {
+	ev := testEnv(t, func(ev *Approach) {
+		ev.Locations = 0
+		ev.Neutrals = 2
+		ev.Aversives = 1
+	})
+	if err := ev.Validate(); err == nil {
+		t.Error("Validate: no error with no drive US locations")
+	}
+	if ev.Drive != -1 || ev.TrgPos != -1 {
+		t.Errorf("Drive: %d TrgPos: %d != -1 with no drive US", ev.Drive, ev.TrgPos)
+	}
+	for _, oracle := range []bool{false, true} {
+		ev.Oracle = oracle
+		for i := 0; i < 50; i++ {
+			ev.Action(ev.Acts[ev.ActGen()], nil)
+			ev.Step()
+		}
+	}
+	for _, oc := range ev.Outcomes {
+		if oc.Success || oc.OptSteps != -1 {
+			t.Errorf("outcome: %+v with no drive US", oc)
+		}
+	}
+}
+
+func TestPatErr(t *testing.T) {
+	ev := testEnv(t, nil)
+	if err := ev.Validate(); err != nil {
+		t.Error(err)
+	}
+	ev = testEnv(t, func(ev *Approach) {
+		ev.PatMinDiff = ev.PatOn + 1
+	})
+	if err := ev.Validate(); err == nil {
+		t.Error("Validate: no error with CS patterns less than PatMinDiff apart")
+	}
+	ev = testEnv(t, func(ev *Approach) {
+		ev.PatOn = ev.PatSize.X*ev.PatSize.Y + 1
+	})
+	if err := ev.Validate(); err == nil {
+		t.Error("Validate: no error with PatOn more than the PatSize")
+	}
+}
+
+func TestOutcomes(t *testing.T) {
+	ev := testEnv(t, func(ev *Approach) {
+		ev.Trial.Max = 10
+	})
+	nstep := 500
+	for i := 0; i < nstep; i++ {
+		ev.Action(ev.Acts[ev.ActGen()], nil)
+		if ev.Step() {
+			continue
+		}
+		ntrl := len(ev.Outcomes)
+		oc := ev.PrvOutcome
+		if ntrl == 0 || oc != ev.Outcomes[ntrl-1] {
+			t.Fatalf("step: %d new trial without the outcome: %+v recorded", i, oc)
+		}
+		if oc.Trial != (ntrl-1)%ev.Trial.Max || ev.Trial.Cur != ntrl%ev.Trial.Max {
+			t.Errorf("trial: %d outcome trial: %d current: %d out of order", ntrl, oc.Trial, ev.Trial.Cur)
+		}
+		if ev.Epoch.Cur != ntrl/ev.Trial.Max {
+			t.Errorf("epoch: %d != %d after: %d trials", ev.Epoch.Cur, ntrl/ev.Trial.Max, ntrl)
+		}
+		if ev.Tick.Cur != 0 || ev.Time != 0 {
+			t.Errorf("tick: %d time: %d not reset at the new trial", ev.Tick.Cur, ev.Time)
+		}
+		if oc.Time < 1 || oc.Time > ev.TimeMax {
+			t.Errorf("outcome: %+v time out of range", oc)
+		}
+		if oc.Success != (oc.US == oc.Drive) || (oc.US < 0 && oc.Time != ev.TimeMax) {
+			t.Errorf("outcome: %+v inconsistent success", oc)
+		}
+	}
+	if ev.Event.Cur != nstep-1 {
+		t.Errorf("event: %d != steps: %d", ev.Event.Cur, nstep-1)
+	}
+	if len(ev.Outcomes) < nstep/ev.TimeMax {
+		t.Errorf("only: %d trials in: %d steps", len(ev.Outcomes), nstep)
+	}
+}
+
+// toTarget moves the env to the target location at zero distance, ready to Consume
+func toTarget(ev *Approach) {
+	ev.Pos = ev.TrgPos
+	ev.Dist = 0
+}
+
+func TestDriveLevels(t *testing.T) {
+	ev := testEnv(t, func(ev *Approach) {
+		ev.DriveRise = 0.1
+		ev.Satiation = 0.6
+	})
+	copy(ev.DriveLevels, []float32{0.2, 0.95, 0.4, 0.1})
+	trg := ev.ChooseDrive()
+	if ev.Drive != 1 || int(ev.States["USs"].Values[trg]) != 1 {
+		t.Fatalf("drive: %d at: %d != highest level drive: 1", ev.Drive, trg)
+	}
+	ev.TrgPos = trg
+	ev.Action("Left", nil)
+	for i, lv := range []float32{0.3, 1, 0.5, 0.2} {
+		if mat32.Abs(ev.DriveLevels[i]-lv) > 1.0e-6 {
+			t.Errorf("drive: %d level: %g != risen: %g", i, ev.DriveLevels[i], lv)
+		}
+	}
+	toTarget(ev)
+	ev.Action("Consume", nil)
+	if ev.US != 1 || ev.Rew != 1 {
+		t.Errorf("US: %d reward: %g != drive: 1 deficit: 1", ev.US, ev.Rew)
+	}
+	if lv := ev.DriveLevels[1]; mat32.Abs(lv-0.4) > 1.0e-6 {
+		t.Errorf("drive level: %g != 0.4 after Satiation", lv)
+	}
+	ev.Step()
+	if oc := ev.PrvOutcome; !oc.Success || oc.Rew != 1 {
+		t.Errorf("outcome: %+v not successful with reward 1", oc)
+	}
+	if ev.Drive == 1 {
+		t.Errorf("drive: 1 still chosen after satiation")
+	}
+}
+
+func TestOutcomeDelay(t *testing.T) {
+	ev := testEnv(t, func(ev *Approach) {
+		ev.DriveRise = 0
+		ev.LocDelays = []int{2, 2, 2, 2}
+		ev.LocMags = []float32{2, 2, 2, 2}
+	})
+	lv := ev.DriveLevels[ev.Drive]
+	toTarget(ev)
+	ev.Action("Consume", nil)
+	if ev.US != -1 || ev.Rew != 0 || ev.PendUS != ev.Drive {
+		t.Fatalf("US: %d reward: %g pending: %d delivered before the delay", ev.US, ev.Rew, ev.PendUS)
+	}
+	for i := 0; i < 2; i++ {
+		if !ev.Step() {
+			t.Fatalf("trial ended at: %d while the US is pending", i)
+		}
+		ev.Action("Left", nil)
+		if i == 0 && (ev.Pos != ev.TrgPos || ev.US != -1) {
+			t.Errorf("pos: %d US: %d changed while the US is pending", ev.Pos, ev.US)
+		}
+	}
+	if ev.US != ev.Drive || mat32.Abs(ev.Rew-2*lv) > 1.0e-6 {
+		t.Errorf("US: %d reward: %g != drive: %d times magnitude: 2", ev.US, ev.Rew, ev.Drive)
+	}
+	if ev.Step() {
+		t.Error("trial did not end after the US was delivered")
+	}
+	if oc := ev.PrvOutcome; !oc.Success || oc.Omitted || oc.Time != 3 {
+		t.Errorf("outcome: %+v not successful after time: 3", oc)
+	}
+}
+
+func TestOutcomeOmit(t *testing.T) {
+	ev := testEnv(t, func(ev *Approach) {
+		ev.LocProbs = []float32{0, 0, 0, 0}
+	})
+	lv := ev.DriveLevels[ev.Drive]
+	toTarget(ev)
+	ev.Action("Consume", nil)
+	if ev.US != -1 || ev.Rew != 0 || ev.DriveLevels[ev.Drive] < lv {
+		t.Errorf("US: %d reward: %g drive level: %g < %g for an omitted US", ev.US, ev.Rew, ev.DriveLevels[ev.Drive], lv)
+	}
+	if ev.Step() {
+		t.Error("trial did not end after an omitted US")
+	}
+	if oc := ev.PrvOutcome; !oc.Success || !oc.Omitted || oc.Rew != 0 {
+		t.Errorf("outcome: %+v not a successful omission", oc)
+	}
+}
+
+func TestSeed(t *testing.T) {
+	run := func(seed int64) []TrialOutcome {
+		ev := testEnv(t, func(ev *Approach) {
+			ev.RndSeed = seed
+			ev.LocProbs = []float32{0.5, 0.5, 0.5, 0.5}
+			ev.MagSD = 0.2
+		})
+		for i := 0; i < 300; i++ {
+			ev.Action(ev.Acts[ev.ActGen()], nil)
+			ev.Step()
+		}
+		return ev.Outcomes
+	}
+	ocs, ocs2, ocs3 := run(3), run(3), run(4)
+	if len(ocs) != len(ocs2) {
+		t.Fatalf("trials: %d != %d with the same seed", len(ocs), len(ocs2))
+	}
+	for i, oc := range ocs {
+		if oc != ocs2[i] {
+			t.Fatalf("trial: %d outcome: %+v != %+v with the same seed", i, oc, ocs2[i])
+		}
+	}
+	same := len(ocs) == len(ocs3)
+	for i := 0; same && i < len(ocs); i++ {
+		same = ocs[i] == ocs3[i]
+	}
+	if same {
+		t.Error("same outcomes with a different seed")
+	}
+}
+
+// checkCSs checks that the CS in each location predicts the US in it
+func checkCSs(t *testing.T, ev *Approach) {
+	uss := ev.States["USs"]
+	css := ev.States["CSs"]
+	for l := 0; l < ev.LocTot; l++ {
+		if us := ev.CSToUS[int(css.Values[l])]; us != int(uss.Values[l]) {
+			t.Errorf("location: %d CS: %g predicts US: %d != %g", l, css.Values[l], us, uss.Values[l])
+		}
+	}
+}
+
+func TestRemapTypes(t *testing.T) {
+	ev := testEnv(t, func(ev *Approach) {
+		ev.CSPerDrive = 2
+	})
+	uss := ev.States["USs"]
+	for _, typ := range []RemapTypes{RemapSwap, RemapCS, RemapReverse, RemapNew} {
+		prvUS := append([]float32{}, uss.Values...)
+		prvCS := append([]int{}, ev.CSToUS...)
+		ev.DoRemap(typ)
+		if n := len(ev.Remaps); n == 0 || ev.Remaps[n-1].Type != typ {
+			t.Errorf("remap: %v not logged", typ)
+		}
+		checkCSs(t, ev)
+		nus, ncs := 0, 0
+		for l, us := range uss.Values {
+			if us != prvUS[l] {
+				nus++
+			}
+		}
+		for p, us := range ev.CSToUS {
+			if us != prvCS[p] {
+				ncs++
+			}
+		}
+		switch typ {
+		case RemapSwap:
+			if nus != 2 || ncs != 0 {
+				t.Errorf("swap: %d USs %d CSs changed != 2, 0", nus, ncs)
+			}
+		case RemapCS:
+			if nus != 0 || ncs != 2 {
+				t.Errorf("CS remap: %d USs %d CSs changed != 0, 2", nus, ncs)
+			}
+		case RemapReverse:
+			if nus != 0 {
+				t.Errorf("reverse: %d USs changed", nus)
+			}
+			for p, us := range ev.CSToUS {
+				if us != (prvCS[p]+1)%ev.Drives {
+					t.Errorf("reverse: CS: %d predicts US: %d != next US after: %d", p, us, prvCS[p])
+				}
+			}
+		}
+	}
+}
+
+func TestRemapSched(t *testing.T) {
+	ev := testEnv(t, func(ev *Approach) {
+		ev.Remap = RemapSched{Type: RemapSwap, Interval: 3}
+		ev.Trial.Max = 1000
+	})
+	for i := 0; i < 500; i++ {
+		ev.Action(ev.Acts[ev.ActGen()], nil)
+		ev.Step()
+		checkCSs(t, ev)
+	}
+	if len(ev.Remaps) != len(ev.Outcomes)/3 {
+		t.Errorf("remaps: %d != every 3 of: %d trials", len(ev.Remaps), len(ev.Outcomes))
+	}
+	for i, re := range ev.Remaps {
+		if re.Trial != 3*(i+1) {
+			t.Errorf("remap: %d at trial: %d != %d", i, re.Trial, 3*(i+1))
+		}
+	}
+
+	ev = testEnv(t, func(ev *Approach) {
+		ev.Remap = RemapSched{Type: RemapReverse, Criterion: 2}
+		ev.Oracle = true
+		ev.Trial.Max = 1000
+	})
+	for i := 0; i < 500; i++ {
+		ev.Action(ev.Acts[ev.ActGen()], nil)
+		if !ev.Step() {
+			if oc := ev.PrvOutcome; !oc.Success || oc.WrongConsumes > 0 {
+				t.Fatalf("oracle outcome: %+v not correct", oc)
+			}
+		}
+	}
+	if len(ev.Remaps) != len(ev.Outcomes)/2 {
+		t.Errorf("remaps: %d != after 2 correct of: %d trials", len(ev.Remaps), len(ev.Outcomes))
+	}
+}
+
+func TestEffort(t *testing.T) {
+	ev := testEnv(t, func(ev *Approach) {
+		ev.DriveRise = 0
+		ev.ActCosts = map[string]float32{"Forward": 0.1, "Left": 0.05, "Right": 0.05, "Consume": 0.2}
+	})
+	lv := ev.DriveLevels[ev.Drive]
+	ev.Action("Left", nil)
+	if ev.Rew != -0.05 || ev.State("Rew").(*etensor.Float32).Values[0] != -0.05 {
+		t.Errorf("reward: %g != -Left cost", ev.Rew)
+	}
+	ev.Step()
+	toTarget(ev)
+	ev.Action("Consume", nil)
+	if mat32.Abs(ev.Rew-(lv-0.2)) > 1.0e-6 {
+		t.Errorf("reward: %g != drive level: %g - Consume cost", ev.Rew, lv)
+	}
+	ev.Step()
+	oc := ev.PrvOutcome
+	if mat32.Abs(oc.Effort-0.25) > 1.0e-6 || mat32.Abs(oc.Return-(lv-0.25)) > 1.0e-6 {
+		t.Errorf("outcome: %+v effort != 0.25", oc)
+	}
+}
+
+func TestDistractors(t *testing.T) {
+	ev := testEnv(t, func(ev *Approach) {
+		ev.Neutrals = 1
+		ev.Aversives = 1
+		ev.AversiveVal = 0.5
+	})
+	if ev.LocTot != 6 || ev.CSTot != 6 {
+		t.Fatalf("locations: %d CSs: %d != 6 with 2 distractors", ev.LocTot, ev.CSTot)
+	}
+	checkCSs(t, ev)
+	uss := ev.States["USs"]
+	for _, dus := range []int{ev.NeutralUS(), ev.AversiveUS()} {
+		for l, us := range uss.Values {
+			if int(us) == dus {
+				ev.Pos = l
+			}
+		}
+		ev.Dist = 0
+		ev.Action("Consume", nil)
+		rew := float32(0)
+		if dus == ev.AversiveUS() {
+			rew = -0.5
+		}
+		if ev.US != dus || ev.Rew != rew || ev.State("US").(*etensor.Float32).Value([]int{0, dus}) != 1 {
+			t.Errorf("US: %d reward: %g != distractor: %d reward: %g", ev.US, ev.Rew, dus, rew)
+		}
+		ev.Step()
+		if oc := ev.PrvOutcome; oc.Success || oc.WrongConsumes != 1 || oc.US != dus {
+			t.Errorf("outcome: %+v not a wrong consume of distractor: %d", oc, dus)
+		}
+	}
+}
+
+func TestDistPop(t *testing.T) {
+	ev := testEnv(t, func(ev *Approach) {
+		ev.DistMax = 20
+		ev.DistPop = true
+		ev.TimeMax = 30
+	})
+	if err := ev.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	ds := ev.State("Dist").(*etensor.Float32)
+	if ds.Dim(1) != ev.PopSize {
+		t.Fatalf("dist shape: %v not PopSize: %d", ds.Shapes(), ev.PopSize)
+	}
+	toTarget(ev)
+	ev.RenderState()
+	near := append([]float32{}, ds.Values...)
+	ev.Dist = ev.DistMax - 1
+	ev.RenderState()
+	for i, v := range near {
+		if v != ds.Values[i] {
+			return
+		}
+	}
+	t.Error("same dist population code at the nearest and farthest distance")
+}
+
+func TestOracle(t *testing.T) {
+	set := func(ev *Approach) {
+		ev.Oracle = true
+		ev.ActCosts = map[string]float32{"Forward": 0.02, "Left": 0.01, "Right": 0.03, "Consume": 0.05}
+		ev.LocDelays = []int{0, 1, 2, 0}
+	}
+	ev := testEnv(t, set)
+	ev.Pos = (ev.TrgPos + 2) % ev.LocTot // tie is broken by the lower cost
+	if act, turns := ev.OracleTurn(); act != "Left" || turns != 2 {
+		t.Errorf("oracle turn: %s %d != Left 2", act, turns)
+	}
+	ev.Pos = (ev.TrgPos + 3) % ev.LocTot
+	if act, turns := ev.OracleTurn(); act != "Right" || turns != 1 {
+		t.Errorf("oracle turn: %s %d != Right 1", act, turns)
+	}
+
+	ev = testEnv(t, set)
+	for i := 0; i < 500; i++ {
+		ev.Action(ev.Acts[ev.ActGen()], nil)
+		ev.Step()
+	}
+	if len(ev.Outcomes) < 50 {
+		t.Fatalf("only: %d oracle trials", len(ev.Outcomes))
+	}
+	for _, oc := range ev.Outcomes {
+		if !oc.Success || oc.Time != oc.OptSteps || oc.Efficiency != 1 {
+			t.Errorf("oracle outcome: %+v not optimal", oc)
+		}
+		if mat32.Abs(oc.Return-oc.OptReturn) > 1.0e-5 {
+			t.Errorf("oracle outcome: %+v return != OptReturn", oc)
+		}
+	}
+
+	ev = testEnv(t, func(ev *Approach) {
+		set(ev)
+		ev.Oracle = false
+	})
+	for i := 0; i < 500; i++ {
+		ev.Action(ev.Acts[ev.ActGen()], nil)
+		ev.Step()
+	}
+	nsub := 0
+	for _, oc := range ev.Outcomes {
+		if oc.Efficiency > 1 || (!oc.Success && oc.Efficiency != 0) {
+			t.Errorf("heuristic outcome: %+v efficiency out of range", oc)
+		}
+		if oc.Success && oc.Efficiency < 1 {
+			nsub++
+		}
+	}
+	if nsub == 0 {
+		t.Error("no suboptimal heuristic trials")
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/goki/mat32"
+)
+
+// OracleTurn returns the turn action (Left or Right) and number of turns
+// on the shortest path from the current position to the target position
+// around the ring of locations -- ties are broken by the lower ActCosts,
+// then Right.  The number of turns is 0 at the target.
+func (ev *Approach) OracleTurn() (act string, turns int) {
+	n := ev.LocTot
+	nl := (ev.Pos - ev.TrgPos + n) % n
+	nr := (ev.TrgPos - ev.Pos + n) % n
+	switch {
+	case nl < nr:
+		return "Left", nl
+	case nr < nl:
+		return "Right", nr
+	case ev.ActCosts["Left"] < ev.ActCosts["Right"]:
+		return "Left", nl
+	}
+	return "Right", nr
+}
+
+// OracleAct returns the optimal action, knowing the US layout: turn toward
+// the target along the shortest path, move Forward to it, and Consume.
+// While waiting for a delayed US, or if there is no target, the cheapest
+// action is returned.
+func (ev *Approach) OracleAct() int {
+	if ev.PendUS >= 0 || ev.TrgPos < 0 {
+		return ev.CheapestAct()
+	}
+	if act, turns := ev.OracleTurn(); turns > 0 {
+		return ev.ActMap[act]
+	}
+	if ev.Dist > 0 {
+		return ev.ActMap["Forward"]
+	}
+	return ev.ActMap["Consume"]
+}
+
+// CheapestAct returns the action with the lowest ActCosts
+func (ev *Approach) CheapestAct() int {
+	mi := 0
+	for i, act := range ev.Acts {
+		if ev.ActCosts[act] < ev.ActCosts[ev.Acts[mi]] {
+			mi = i
+		}
+	}
+	return mi
+}
+
+// OracleSteps returns the minimum number of steps from the current state
+// to consume the target US (turns, Forward moves and Consume), and the
+// number of further steps until it is delivered, from the LocDelays.
+// Returns -1 for steps if it cannot be consumed before TimeMax, or there
+// is no target.
+func (ev *Approach) OracleSteps() (steps, delay int) {
+	if ev.TrgPos < 0 {
+		return -1, 0
+	}
+	_, turns := ev.OracleTurn()
+	steps = turns + ev.Dist + 1
+	if ev.Time+steps > ev.TimeMax {
+		return -1, 0
+	}
+	return steps, ev.LocDelays[ev.TrgPos]
+}
+
+// OracleReturn returns the expected return for the shortest path from the
+// current state: the expected reward for the target US, from its LocProbs,
+// LocMags and the level of its drive when delivered, minus the ActCosts of
+// the path.  If the target cannot be reached before TimeMax, it is the cost
+// of taking the cheapest action until then.
+func (ev *Approach) OracleReturn() float32 {
+	steps, delay := ev.OracleSteps()
+	if steps < 0 {
+		return -float32(ev.TimeMax-ev.Time) * ev.ActCosts[ev.Acts[ev.CheapestAct()]]
+	}
+	act, turns := ev.OracleTurn()
+	cost := float32(turns)*ev.ActCosts[act] + float32(ev.Dist)*ev.ActCosts["Forward"] + ev.ActCosts["Consume"]
+	cost += float32(delay) * ev.ActCosts[ev.Acts[ev.CheapestAct()]]
+	lv := mat32.Min(ev.DriveLevels[ev.Drive]+float32(steps+delay)*ev.DriveRise, 1)
+	rew := ev.LocProbs[ev.TrgPos] * ev.LocMags[ev.TrgPos] * lv
+	return rew - cost
+}
+
+// OracleStart records the optimal steps and return for the current trial
+// into the Outcome -- called at the start of each trial
+func (ev *Approach) OracleStart() {
+	steps, delay := ev.OracleSteps()
+	if steps >= 0 {
+		steps += delay
+	}
+	ev.Outcome.OptSteps = steps
+	ev.Outcome.OptReturn = ev.OracleReturn()
+}
+
+// OracleEnd computes the actual return and the efficiency of the current
+// trial relative to the optimal -- called at the end of each trial
+func (ev *Approach) OracleEnd() {
+	oc := &ev.Outcome
+	oc.Return = oc.Rew - oc.Effort
+	oc.Efficiency = 0
+	if oc.Success && oc.Time > 0 && oc.OptSteps > 0 {
+		oc.Efficiency = float32(oc.OptSteps) / float32(oc.Time)
+	}
+}
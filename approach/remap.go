@@ -0,0 +1,164 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/goki/ki/kit"
+)
+
+//go:generate stringer -type=RemapTypes
+
+// RemapTypes are the ways of remapping the USs and CSs in locations,
+// for reversal-learning experiments
+type RemapTypes int32
+
+const (
+	// RemapNew generates a new random set of USs and CSs in all locations,
+	// starting a new Episode -- as done every NewStateInt trials
+	RemapNew RemapTypes = iota
+
+	// RemapSwap swaps the USs (with their CSs) of two random locations
+	RemapSwap
+
+	// RemapCS swaps one CS of each of two random USs, so that each CS now
+	// predicts the other US -- with CSPerDrive > 1, the other CSs for these
+	// USs keep their mapping
+	RemapCS
+
+	// RemapReverse reverses the entire CS -> US mapping, so that the CSs
+	// for each drive US now predict the next US, while the USs stay in their
+	// locations -- with 2 drives, this is a classic reversal
+	RemapReverse
+
+	RemapTypesN
+)
+
+var KiT_RemapTypes = kit.Enums.AddEnum(RemapTypesN, kit.NotBitFlag, nil)
+
+func (ev RemapTypes) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *RemapTypes) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+
+// RemapSched is a schedule for remapping the USs and CSs in locations,
+// triggered at the start of a trial after a fixed number of trials, or
+// after a criterion number of consecutive correct approaches
+type RemapSched struct {
+
+	// type of remapping to do
+	Type RemapTypes `desc:"type of remapping to do"`
+
+	// number of trials between remappings -- 0 = not used
+	Interval int `desc:"number of trials between remappings -- 0 = not used"`
+
+	// number of consecutive correct approaches (consuming the target US without any wrong consumes) that triggers a remapping -- 0 = not used
+	Criterion int `desc:"number of consecutive correct approaches (consuming the target US without any wrong consumes) that triggers a remapping -- 0 = not used"`
+}
+
+// RemapEvent records one remapping of the USs and CSs in locations
+type RemapEvent struct {
+
+	// Trial counter value for the first trial with the new mapping
+	Trial int `desc:"Trial counter value for the first trial with the new mapping"`
+
+	// Event counter value when the remapping happened
+	Event int `desc:"Event counter value when the remapping happened"`
+
+	// type of remapping
+	Type RemapTypes `desc:"type of remapping"`
+
+	// description of what changed
+	Desc string `desc:"description of what changed"`
+}
+
+// String returns the remap event as a string, for logging
+func (re *RemapEvent) String() string {
+	return fmt.Sprintf("Trial: %d Event: %d %s: %s", re.Trial, re.Event, re.Type, re.Desc)
+}
+
+// InitCSMap initializes the CS -> US mapping so that each of the USTypes
+// is predicted by CSPerDrive consecutive CS patterns
+func (ev *Approach) InitCSMap() {
+	ust := ev.USTypes()
+	ev.CSToUS = make([]int, ev.CSTot)
+	for p := range ev.CSToUS {
+		ev.CSToUS[p] = ust[p/ev.CSPerDrive]
+	}
+}
+
+// CSForUS returns a random CS pattern that currently predicts given US
+func (ev *Approach) CSForUS(us int) int {
+	var pats []int
+	for p, u := range ev.CSToUS {
+		if u == us {
+			pats = append(pats, p)
+		}
+	}
+	return pats[ev.Rand.Intn(len(pats), -1)]
+}
+
+// RedrawCSs draws new CSs for locations whose CS no longer predicts
+// the US in that location
+func (ev *Approach) RedrawCSs() {
+	uss := ev.States["USs"]
+	css := ev.States["CSs"]
+	for l := 0; l < ev.LocTot; l++ {
+		us := int(uss.Values[l])
+		if ev.CSToUS[int(css.Values[l])] != us {
+			css.Values[l] = float32(ev.CSForUS(us))
+		}
+	}
+}
+
+// CheckRemap checks the Remap schedule, doing the remapping if it is
+// triggered -- called at the start of each trial
+func (ev *Approach) CheckRemap() {
+	rs := &ev.Remap
+	if (rs.Interval > 0 && ev.RemapCtr >= rs.Interval) || (rs.Criterion > 0 && ev.Consec >= rs.Criterion) {
+		ev.DoRemap(rs.Type)
+		ev.RemapCtr = 0
+		ev.Consec = 0
+	}
+}
+
+// DoRemap does given type of remapping, and records it in Remaps
+func (ev *Approach) DoRemap(typ RemapTypes) {
+	uss := ev.States["USs"]
+	css := ev.States["CSs"]
+	desc := ""
+	switch typ {
+	case RemapNew:
+		ev.GenState()
+		desc = fmt.Sprintf("USs: %v", uss.Values)
+	case RemapSwap:
+		if ev.LocTot < 2 {
+			return
+		}
+		lp := ev.Rand.Perm(ev.LocTot, -1)
+		l1, l2 := lp[0], lp[1]
+		uss.Values[l1], uss.Values[l2] = uss.Values[l2], uss.Values[l1]
+		css.Values[l1], css.Values[l2] = css.Values[l2], css.Values[l1]
+		desc = fmt.Sprintf("locations: %d <-> %d", l1, l2)
+	case RemapCS:
+		if ev.Drives < 2 {
+			return
+		}
+		dp := ev.Rand.Perm(ev.Drives, -1)
+		u1, u2 := dp[0], dp[1]
+		p1, p2 := ev.CSForUS(u1), ev.CSForUS(u2)
+		ev.CSToUS[p1], ev.CSToUS[p2] = u2, u1
+		ev.RedrawCSs()
+		desc = fmt.Sprintf("CS: %d -> US: %d, CS: %d -> US: %d", p1, u2, p2, u1)
+	case RemapReverse:
+		for p, u := range ev.CSToUS {
+			if u < ev.Drives { // distractors keep their CSs
+				ev.CSToUS[p] = (u + 1) % ev.Drives
+			}
+		}
+		ev.RedrawCSs()
+		desc = fmt.Sprintf("CS -> US: %v", ev.CSToUS)
+	}
+	ev.Remaps = append(ev.Remaps, RemapEvent{Trial: ev.Trial.Cur, Event: ev.Event.Cur, Type: typ, Desc: desc})
+}
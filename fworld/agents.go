@@ -0,0 +1,371 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/emer/emergent/evec"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/mat32"
+)
+
+// Agent has all the state for one agent in the world: its pose,
+// interoceptive state, and rendered sensory states.  The FWorld embeds
+// a pointer to the current agent, so these fields are accessed directly
+// through the FWorld (e.g., ev.PosF) -- use SetAgent to change which
+// agent that is.
+type Agent struct {
+
+	// unique identifier of the agent
+	ID string `desc:"unique identifier of the agent"`
+
+	// current location of agent, floating point
+	PosF mat32.Vec2 `inactive:"+" desc:"current location of agent, floating point"`
+
+	// current location of agent, integer
+	PosI evec.Vec2i `inactive:"+" desc:"current location of agent, integer"`
+
+	// current angle, in degrees
+	Angle int `inactive:"+" desc:"current angle, in degrees"`
+
+	// angle that we just rotated -- drives vestibular
+	RotAng int `inactive:"+" desc:"angle that we just rotated -- drives vestibular"`
+
+	// current heading angle in Continuous mode, in degrees -- Angle is this rounded to nearest degree
+	AngleF float32 `inactive:"+" desc:"current heading angle in Continuous mode, in degrees -- Angle is this rounded to nearest degree"`
+
+	// current linear velocity in Continuous mode, in cells per tick -- negative = backward
+	Speed float32 `inactive:"+" desc:"current linear velocity in Continuous mode, in cells per tick -- negative = backward"`
+
+	// current angular velocity in Continuous mode, in degrees per tick -- positive = left
+	AngVel float32 `inactive:"+" desc:"current angular velocity in Continuous mode, in degrees per tick -- positive = left"`
+
+	// linear acceleration on the last tick in Continuous mode -- drives vestibular
+	LinAcc float32 `inactive:"+" desc:"linear acceleration on the last tick in Continuous mode -- drives vestibular"`
+
+	// angular acceleration on the last tick in Continuous mode -- drives vestibular
+	AngAcc float32 `inactive:"+" desc:"angular acceleration on the last tick in Continuous mode -- drives vestibular"`
+
+	// normalized linear velocity (-1..1 of MaxSpeed) for the Move action in Continuous mode -- set from Action input, or directly before ActionAgents
+	MoveLin float32 `desc:"normalized linear velocity (-1..1 of MaxSpeed) for the Move action in Continuous mode -- set from Action input, or directly before ActionAgents"`
+
+	// normalized angular velocity (-1..1 of MaxAngVel) for the Move action in Continuous mode, positive = left -- set from Action input, or directly before ActionAgents
+	MoveAng float32 `desc:"normalized angular velocity (-1..1 of MaxAngVel) for the Move action in Continuous mode, positive = left -- set from Action input, or directly before ActionAgents"`
+
+	// last action taken
+	Act int `inactive:"+" desc:"last action taken"`
+
+	// depth for each angle (NFOVRays), raw
+	Depths []float32 `desc:"depth for each angle (NFOVRays), raw"`
+
+	// depth for each angle (NFOVRays), normalized log
+	DepthLogs []float32 `desc:"depth for each angle (NFOVRays), normalized log"`
+
+	// material at each angle
+	ViewMats []int `inactive:"+" desc:"material at each angle"`
+
+	// materials at fovea, L-R
+	FovMats []int `desc:"materials at fovea, L-R"`
+
+	// raw depths to foveal materials, L-R
+	FovDepths []float32 `desc:"raw depths to foveal materials, L-R"`
+
+	// normalized log depths to foveal materials, L-R
+	FovDepthLogs []float32 `desc:"normalized log depths to foveal materials, L-R"`
+
+	// material at each right angle: front, left, right back
+	ProxMats []int `desc:"material at each right angle: front, left, right back"`
+
+	// coordinates for proximal grid points: front, left, right, back
+	ProxPos []evec.Vec2i `desc:"coordinates for proximal grid points: front, left, right, back"`
+
+	// floating point value of internal states -- dim of Inters
+	InterStates map[string]float32 `inactive:"+" desc:"floating point value of internal states -- dim of Inters"`
+
+	// true if the agent died on the last action, from a Lethal drive
+	Dead bool `inactive:"+" desc:"true if the agent died on the last action, from a Lethal drive"`
+
+	// number of times the agent has died since Init
+	Deaths int `inactive:"+" desc:"number of times the agent has died since Init"`
+
+	// material consumed on the last action, if any
+	Consumed string `inactive:"+" desc:"material consumed on the last action, if any"`
+
+	// current drift of the vestibular signals, from sensor Noise
+	VestDrift float32 `inactive:"+" desc:"current drift of the vestibular signals, from sensor Noise"`
+
+	// odor concentration at the nose for each odor type
+	OdorConcs []float32 `inactive:"+" desc:"odor concentration at the nose for each odor type"`
+
+	// normalized left - right nostril odor difference for each odor type, -1..1, positive = stronger on left
+	OdorLRs []float32 `inactive:"+" desc:"normalized left - right nostril odor difference for each odor type, -1..1, positive = stronger on left"`
+
+	// current rendered state tensors -- extensible map
+	CurStates map[string]*etensor.Float32 `desc:"current rendered state tensors -- extensible map"`
+
+	// next rendered state tensors -- updated from actions
+	NextStates map[string]*etensor.Float32 `desc:"next rendered state tensors -- updated from actions"`
+}
+
+// IncState increments state by factor, keeping bounded between 0-1
+func (ag *Agent) IncState(nm string, inc float32) {
+	st := ag.InterStates[nm]
+	st += inc
+	st = mat32.Max(st, 0)
+	st = mat32.Min(st, 1)
+	ag.InterStates[nm] = st
+}
+
+// Clone returns a deep copy of the agent
+func (ag *Agent) Clone() *Agent {
+	cp := &Agent{}
+	*cp = *ag
+	cp.Depths = append([]float32{}, ag.Depths...)
+	cp.DepthLogs = append([]float32{}, ag.DepthLogs...)
+	cp.ViewMats = append([]int{}, ag.ViewMats...)
+	cp.FovMats = append([]int{}, ag.FovMats...)
+	cp.FovDepths = append([]float32{}, ag.FovDepths...)
+	cp.FovDepthLogs = append([]float32{}, ag.FovDepthLogs...)
+	cp.ProxMats = append([]int{}, ag.ProxMats...)
+	cp.ProxPos = append([]evec.Vec2i{}, ag.ProxPos...)
+	cp.InterStates = make(map[string]float32, len(ag.InterStates))
+	for k, v := range ag.InterStates {
+		cp.InterStates[k] = v
+	}
+	cp.OdorConcs = append([]float32{}, ag.OdorConcs...)
+	cp.OdorLRs = append([]float32{}, ag.OdorLRs...)
+	cp.CurStates = CopyStateMap(ag.CurStates)
+	cp.NextStates = CopyStateMap(ag.NextStates)
+	return cp
+}
+
+// CopyFrom copies the state from given source agent, which must have
+// been configured the same way, into the existing state of this agent
+func (ag *Agent) CopyFrom(src *Agent) {
+	ag.ID = src.ID
+	ag.PosF = src.PosF
+	ag.PosI = src.PosI
+	ag.Angle = src.Angle
+	ag.RotAng = src.RotAng
+	ag.AngleF = src.AngleF
+	ag.Speed = src.Speed
+	ag.AngVel = src.AngVel
+	ag.LinAcc = src.LinAcc
+	ag.AngAcc = src.AngAcc
+	ag.MoveLin = src.MoveLin
+	ag.MoveAng = src.MoveAng
+	ag.Act = src.Act
+	copy(ag.Depths, src.Depths)
+	copy(ag.DepthLogs, src.DepthLogs)
+	copy(ag.ViewMats, src.ViewMats)
+	copy(ag.FovMats, src.FovMats)
+	copy(ag.FovDepths, src.FovDepths)
+	copy(ag.FovDepthLogs, src.FovDepthLogs)
+	copy(ag.ProxMats, src.ProxMats)
+	copy(ag.ProxPos, src.ProxPos)
+	for k, v := range src.InterStates {
+		ag.InterStates[k] = v
+	}
+	ag.Dead = src.Dead
+	ag.Deaths = src.Deaths
+	ag.Consumed = src.Consumed
+	ag.VestDrift = src.VestDrift
+	copy(ag.OdorConcs, src.OdorConcs)
+	copy(ag.OdorLRs, src.OdorLRs)
+	SetStateMap(ag.CurStates, src.CurStates)
+	SetStateMap(ag.NextStates, src.NextStates)
+}
+
+// AddAgent adds a new agent with given ID -- must call ConfigImpl after this
+// to configure the agent's states
+func (ev *FWorld) AddAgent(id string) *Agent {
+	ag := &Agent{ID: id}
+	ev.Agents = append(ev.Agents, ag)
+	return ag
+}
+
+// ConfigAgentsImpl does the automatic parts of configuring the agents,
+// configuring the states for each agent in turn, and sets the first
+// agent as the current one
+func (ev *FWorld) ConfigAgentsImpl() {
+	if len(ev.Agents) == 0 {
+		ev.AddAgent("Agent0")
+	}
+	ev.AgentMap = make(map[string]int, len(ev.Agents))
+	for i, ag := range ev.Agents {
+		ev.AgentMap[ag.ID] = i
+		ev.Agent = ag
+		ev.ConfigAgentImpl()
+	}
+	ev.AgentMat = ev.MatMap["Agent"]
+	ev.SetAgentIdx(0)
+}
+
+// SetAgentIdx sets the current agent by index in Agents
+func (ev *FWorld) SetAgentIdx(idx int) {
+	ev.CurAgent = idx
+	ev.Agent = ev.Agents[idx]
+}
+
+// SetAgent sets the current agent by ID -- the agent's state is then
+// accessed directly through the FWorld, and receives the Action calls
+func (ev *FWorld) SetAgent(id string) error {
+	idx, ok := ev.AgentMap[id]
+	if !ok {
+		return fmt.Errorf("FWorld: %v agent not found: %s", ev.Nm, id)
+	}
+	ev.SetAgentIdx(idx)
+	return nil
+}
+
+// AgentsDo calls given function for each agent, in order, with that
+// agent set as the current agent, restoring the current agent after.
+func (ev *FWorld) AgentsDo(fun func(ag *Agent)) {
+	cur := ev.CurAgent
+	for i, ag := range ev.Agents {
+		ev.SetAgentIdx(i)
+		fun(ag)
+	}
+	ev.SetAgentIdx(cur)
+}
+
+// AgentState returns given current state element for given agent ID
+func (ev *FWorld) AgentState(id, element string) etensor.Tensor {
+	idx, ok := ev.AgentMap[id]
+	if !ok {
+		return nil
+	}
+	return ev.Agents[idx].CurStates[element]
+}
+
+// InitAgents initializes the state of all the agents: the first
+// starts in the middle of the world, and the others at random empty locations
+func (ev *FWorld) InitAgents() {
+	ev.AgentsDo(func(ag *Agent) {
+		if ev.CurAgent == 0 {
+			ev.InitAgent(ev.Size.DivScalar(2)) // start in middle -- could be random..
+		} else {
+			p, ok := ev.RandomEmpty()
+			if !ok {
+				p = ev.Size.DivScalar(2)
+			}
+			ev.InitAgent(p)
+		}
+		ag.Deaths = 0
+	})
+}
+
+// InitAgent initializes the pose of the current agent at given position,
+// facing angle 0 at rest, with its drives at their initial levels
+func (ev *FWorld) InitAgent(pos evec.Vec2i) {
+	ev.PosI = pos
+	ev.PosF = pos.ToVec2()
+	for i := 0; i < 4; i++ {
+		ev.ProxMats[i] = 0
+	}
+
+	ev.Angle = 0
+	ev.RotAng = 0
+	ev.AngleF = 0
+	ev.Speed = 0
+	ev.AngVel = 0
+	ev.LinAcc = 0
+	ev.AngAcc = 0
+	ev.VestDrift = 0
+	ev.Dead = false
+	ev.InitDrives()
+}
+
+// AgentAt returns the agent at given point, other than given one
+// (typically the current agent), or nil if none
+func (ev *FWorld) AgentAt(p evec.Vec2i, except *Agent) *Agent {
+	for _, ag := range ev.Agents {
+		if ag != except && ag.PosI == p {
+			return ag
+		}
+	}
+	return nil
+}
+
+// NearestAgent returns the agent nearest to given point
+func (ev *FWorld) NearestAgent(p mat32.Vec2) *Agent {
+	var near *Agent
+	mind := float32(-1)
+	for _, ag := range ev.Agents {
+		d := ag.PosF.DistTo(p)
+		if mind < 0 || d < mind {
+			near = ag
+			mind = d
+		}
+	}
+	return near
+}
+
+// MatAt returns the material as seen by the current agent at given point:
+// AgentMat if another agent is there, otherwise the world material
+func (ev *FWorld) MatAt(p evec.Vec2i) int {
+	if ev.AgentMat > 0 && ev.AgentAt(p, ev.Agent) != nil {
+		return ev.AgentMat
+	}
+	return ev.GetWorld(p)
+}
+
+// IsBarrier returns true if given material blocks movement:
+// barriers, landmark cue cards and other agents
+func (ev *FWorld) IsBarrier(mat int) bool {
+	return mat > 0 && (mat <= ev.BarrierIdx || ev.LandmarkMats[mat] || mat == ev.AgentMat)
+}
+
+// ActionAgents takes the given actions for each agent, keyed by agent ID,
+// with agents not in the map taking the Stay action.  Agents act in the
+// order of the Agents list, or in a random order if ShuffleAgents, so that
+// earlier agents get first access to any contested consumables.
+// Then the entities are updated and the resulting state is rendered
+// for all agents.  Call Step after this as usual.
+func (ev *FWorld) ActionAgents(acts map[string]string) {
+	cur := ev.CurAgent
+	grp := ev.NewActGroup()
+	var order []int
+	if ev.ShuffleAgents && len(ev.Agents) > 1 {
+		order = ev.Rand.Perm(len(ev.Agents), -1)
+	} else {
+		order = make([]int, len(ev.Agents))
+		for i := range order {
+			order[i] = i
+		}
+	}
+	for _, ai := range order {
+		ev.SetAgentIdx(ai)
+		action, ok := acts[ev.ID]
+		if !ok {
+			action = "Stay"
+		}
+		a, ok := ev.ActMap[action]
+		if !ok {
+			fmt.Printf("Action not recognized: %s\n", action)
+			a = ev.ActMap["Stay"]
+		}
+		ev.RecordAct(action, grp)
+		ev.Act = a
+		ev.DoAct(ev.Act)
+	}
+	ev.UpdateEntities()
+	ev.AgentsDo(func(ag *Agent) {
+		ev.ScanState()
+	})
+	ev.SetAgentIdx(cur)
+	ev.CheckEpisode()
+}
+
+// ActGenAgents generates an action for each agent using ActGen,
+// returning a map keyed by agent ID suitable for ActionAgents
+func (ev *FWorld) ActGenAgents() map[string]string {
+	acts := make(map[string]string, len(ev.Agents))
+	ev.AgentsDo(func(ag *Agent) {
+		acts[ag.ID] = ev.Acts[ev.ActGen()]
+	})
+	return acts
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/emer/etable/etensor"
+	"github.com/goki/mat32"
+)
+
+// ConfigDaylight configures the default parameters for the day-night cycle
+func (ev *FWorld) ConfigDaylight() {
+	ev.Params["DayLen"] = 0           // ticks per day-night cycle, starting at noon -- 0 = always day
+	ev.Params["NightRange"] = 5       // visual range in the dark, in cells -- range increases linearly with Light up to unlimited at full light
+	ev.Params["NightContrast"] = 0.2  // contrast of fovea patterns in the dark -- increases linearly with Light up to 1 at full light
+	ev.Params["FoodRefreshNight"] = 0 // time steps before food is refreshed at night -- 0 = same as FoodRefresh
+	ev.Params["WaterRefreshNight"] = 0
+}
+
+// ConfigClockImpl configures the Clock state for the current agent
+func (ev *FWorld) ConfigClockImpl() {
+	cs := &etensor.Float32{}
+	cs.SetShape([]int{2, ev.PopSize}, nil, []string{"SinCos", "Pop"})
+	ev.NextStates["Clock"] = cs
+}
+
+// UpdateLight updates the DayPhase, Light, VisRange and Contrast for the
+// current Tick.  Light follows a cosine over the day, from 1 at noon
+// (phase 0) to 0 at midnight (phase .5).
+func (ev *FWorld) UpdateLight() {
+	dl := ev.Params["DayLen"]
+	if dl <= 0 {
+		ev.DayPhase = 0
+		ev.Light = 1
+		ev.VisRange = 0
+		ev.Contrast = 1
+		return
+	}
+	t := float32(ev.Tick.Cur)
+	if t < 0 {
+		t = 0
+	}
+	ev.DayPhase = (t - dl*mat32.Floor(t/dl)) / dl
+	ev.Light = 0.5 + 0.5*mat32.Cos(2*mat32.Pi*ev.DayPhase)
+	nr := ev.Params["NightRange"]
+	maxd := mat32.Sqrt(float32(ev.Size.X*ev.Size.X + ev.Size.Y*ev.Size.Y))
+	ev.VisRange = nr + (maxd-nr)*ev.Light
+	nc := ev.Params["NightContrast"]
+	ev.Contrast = nc + (1-nc)*ev.Light
+}
+
+// IsNight returns true if it is currently night, when Light < .5
+func (ev *FWorld) IsNight() bool {
+	return ev.Light < 0.5
+}
+
+// InVisRange returns true if given distance is within the current VisRange
+func (ev *FWorld) InVisRange(dist float32) bool {
+	return ev.VisRange <= 0 || dist <= ev.VisRange
+}
+
+// ContrastPat applies the current Contrast to given fovea pattern values,
+// moving each value toward the mean of the pattern
+func (ev *FWorld) ContrastPat(vals []float32) {
+	if ev.Contrast >= 1 || len(vals) == 0 {
+		return
+	}
+	mn := float32(0)
+	for _, v := range vals {
+		mn += v
+	}
+	mn /= float32(len(vals))
+	for i, v := range vals {
+		vals[i] = mn + ev.Contrast*(v-mn)
+	}
+}
+
+// RenderClock renders the clock state: the sine and cosine of the
+// DayPhase, so that the cycle is represented continuously
+func (ev *FWorld) RenderClock() {
+	cs := ev.NextStates["Clock"]
+	a := 2 * mat32.Pi * ev.DayPhase
+	sv := cs.SubSpace([]int{0}).(*etensor.Float32)
+	ev.PopCode.Encode(&sv.Values, 0.5+0.5*mat32.Sin(a), ev.PopSize, false)
+	sv = cs.SubSpace([]int{1}).(*etensor.Float32)
+	ev.PopCode.Encode(&sv.Values, 0.5+0.5*mat32.Cos(a), ev.PopSize, false)
+}
@@ -0,0 +1,383 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/emer/etable/etensor"
+	"github.com/goki/mat32"
+)
+
+// Drive is an interoceptive drive, such as Energy or Hydra, whose level
+// (0-1) in the corresponding Inters state decays with time and effort,
+// and is replenished by consuming a material in front of the agent.
+// Drives are configured from the Params and DriveMats in ConfigDrives,
+// so new drives can be added entirely through configuration.
+type Drive struct {
+
+	// name of the interoceptive state for this drive -- must be in Inters
+	Inter string `desc:"name of the interoceptive state for this drive -- must be in Inters"`
+
+	// initial level at Init and after death
+	Init float32 `desc:"initial level at Init and after death"`
+
+	// decrement per tick due to the passage of time
+	Decay float32 `desc:"decrement per tick due to the passage of time"`
+
+	// additional decrement for moving one step
+	MoveCost float32 `desc:"additional decrement for moving one step"`
+
+	// additional decrement for rotating by AngInc
+	RotCost float32 `desc:"additional decrement for rotating by AngInc"`
+
+	// additional decrement for bumping into a barrier
+	BumpCost float32 `desc:"additional decrement for bumping into a barrier"`
+
+	// additional decrement for taking each named action, e.g., for effortful actions such as Dig
+	ActCosts map[string]float32 `desc:"additional decrement for taking each named action, e.g., for effortful actions such as Dig"`
+
+	// material that replenishes this drive when consumed, if in front of the agent -- empty = none
+	Mat string `desc:"material that replenishes this drive when consumed, if in front of the agent -- empty = none"`
+
+	// action that consumes the Mat, e.g., Eat
+	Act string `desc:"action that consumes the Mat, e.g., Eat"`
+
+	// increment in level for consuming one unit of Mat, at zero level (see SatPow)
+	Val float32 `desc:"increment in level for consuming one unit of Mat, at zero level (see SatPow)"`
+
+	// satiation curve exponent: consumption increment and reward are multiplied by (1 - level)^SatPow, so that consumption has less effect as the drive is satiated -- 0 = no satiation
+	SatPow float32 `desc:"satiation curve exponent: consumption increment and reward are multiplied by (1 - level)^SatPow, so that consumption has less effect as the drive is satiated -- 0 = no satiation"`
+
+	// material that replaces the Mat after it is consumed, e.g., FoodWas -- empty = Mat is not used up
+	UsedMat string `desc:"material that replaces the Mat after it is consumed, e.g., FoodWas -- empty = Mat is not used up"`
+
+	// number of ticks after consumption when the Mat is refreshed
+	Refresh int `desc:"number of ticks after consumption when the Mat is refreshed"`
+
+	// number of ticks after consumption when the Mat is refreshed, at night (see DayLen) -- 0 = same as Refresh
+	NightRefresh int `desc:"number of ticks after consumption when the Mat is refreshed, at night (see DayLen) -- 0 = same as Refresh"`
+
+	// name of an interoceptive state that signals reward for consumption for one tick, e.g., FoodRew -- empty = none
+	RewInter string `desc:"name of an interoceptive state that signals reward for consumption for one tick, e.g., FoodRew -- empty = none"`
+
+	// decrements to other drive levels from consuming the Mat, e.g., Hydra for eating
+	ConsumeCosts map[string]float32 `desc:"decrements to other drive levels from consuming the Mat, e.g., Hydra for eating"`
+
+	// number of instances of Mat to place at random in GenWorld
+	GenN int `desc:"number of instances of Mat to place at random in GenWorld"`
+
+	// urgency nonlinearity exponent: urgency = (1 - level)^UrgPow -- 1 = linear, > 1 = urgency only rises when deficit is large, < 1 = rises quickly
+	UrgPow float32 `desc:"urgency nonlinearity exponent: urgency = (1 - level)^UrgPow -- 1 = linear, > 1 = urgency only rises when deficit is large, < 1 = rises quickly"`
+
+	// if true, the agent dies when the level falls to DeathThr or below, which resets all the drives to their Init levels
+	Lethal bool `desc:"if true, the agent dies when the level falls to DeathThr or below, which resets all the drives to their Init levels"`
+
+	// [viewif: Lethal] level at or below which the agent dies, if Lethal
+	DeathThr float32 `viewif:"Lethal" desc:"level at or below which the agent dies, if Lethal"`
+}
+
+// AddDrive adds a new drive for given interoceptive state, with default
+// parameters that can be further configured on the returned drive
+func (ev *FWorld) AddDrive(inter string) *Drive {
+	dr := &Drive{Inter: inter, Init: 1, UrgPow: 1}
+	dr.ActCosts = make(map[string]float32)
+	dr.ConsumeCosts = make(map[string]float32)
+	ev.Drives = append(ev.Drives, dr)
+	return dr
+}
+
+// DriveMat specifies the drive replenished by a material in DriveMats
+type DriveMat struct {
+
+	// name of the interoceptive state of the drive that is replenished by the material -- must be in Inters
+	Inter string `desc:"name of the interoceptive state of the drive that is replenished by the material -- must be in Inters"`
+
+	// action that consumes the material, e.g., Eat
+	Act string `desc:"action that consumes the material, e.g., Eat"`
+
+	// material that replaces the material after it is consumed, e.g., FoodWas -- empty = not used up
+	UsedMat string `desc:"material that replaces the material after it is consumed, e.g., FoodWas -- empty = not used up"`
+
+	// name of an interoceptive state that signals reward for consumption for one tick, e.g., FoodRew -- empty = none
+	RewInter string `desc:"name of an interoceptive state that signals reward for consumption for one tick, e.g., FoodRew -- empty = none"`
+}
+
+// DriveParamKeys are the per-drive Params keys read by ConfigDrives, each
+// preceded by the drive's Inters name, e.g., EnergyDecay, with the Drive
+// field that it sets.  In addition, <Inter>Cost:<Act> sets ActCosts for
+// the action, and <Inter>Consume:<Inter> sets ConsumeCosts for the other drive.
+var DriveParamKeys = []string{"Init", "Decay", "MoveCost", "RotCost", "BumpCost", "Val", "Satiate", "Refresh", "RefreshNight", "GenN", "UrgPow", "DeathThr"}
+
+// DriveParamAliases maps the original Params names for the Energy and Hydra
+// drives onto the per-drive keys that they set in ConfigDrives -- the
+// per-drive key takes precedence if it is also set
+var DriveParamAliases = map[string]string{
+	"EatVal":            "EnergyVal",
+	"DrinkVal":          "HydraVal",
+	"EatCost":           "EnergyConsume:Hydra",
+	"DrinkCost":         "HydraConsume:Energy",
+	"FoodRefresh":       "EnergyRefresh",
+	"WaterRefresh":      "HydraRefresh",
+	"FoodRefreshNight":  "EnergyRefreshNight",
+	"WaterRefreshNight": "HydraRefreshNight",
+}
+
+// ConfigDrives configures a Drive for each Inters state that is replenished
+// by a material in DriveMats, or has a <Inter>Decay param, in the order of
+// Inters, with the parameters from the per-drive Params (see DriveParamKeys):
+// Init (default 1), Decay, MoveCost, RotCost, BumpCost (default the TimeCost,
+// MoveCost, RotCost, BumpCost params), Val, Satiate (SatPow), Refresh,
+// RefreshNight, GenN (default 0), UrgPow (default 1), and DeathThr, which
+// makes the drive Lethal if present.  The DriveParamAliases can also be
+// used to set the Energy and Hydra params.
+func (ev *FWorld) ConfigDrives() {
+	ev.Drives = nil
+	for _, is := range ev.Inters {
+		mat, dm := ev.DriveMatFor(is)
+		_, decay := ev.Params[is+"Decay"]
+		if dm == nil && !decay {
+			continue
+		}
+		dr := ev.AddDrive(is)
+		if dm != nil {
+			dr.Mat, dr.Act, dr.UsedMat, dr.RewInter = mat, dm.Act, dm.UsedMat, dm.RewInter
+		}
+		dr.Init = ev.DriveParam(is, "Init", 1)
+		dr.Decay = ev.DriveParam(is, "Decay", ev.Params["TimeCost"])
+		dr.MoveCost = ev.DriveParam(is, "MoveCost", ev.Params["MoveCost"])
+		dr.RotCost = ev.DriveParam(is, "RotCost", ev.Params["RotCost"])
+		dr.BumpCost = ev.DriveParam(is, "BumpCost", ev.Params["BumpCost"])
+		dr.Val = ev.DriveParam(is, "Val", 0)
+		dr.SatPow = ev.DriveParam(is, "Satiate", 0)
+		dr.Refresh = int(ev.DriveParam(is, "Refresh", 0))
+		dr.NightRefresh = int(ev.DriveParam(is, "RefreshNight", 0))
+		dr.GenN = int(ev.DriveParam(is, "GenN", 0))
+		dr.UrgPow = ev.DriveParam(is, "UrgPow", 1)
+		if thr, ok := ev.DriveParamVal(is + "DeathThr"); ok {
+			dr.Lethal = true
+			dr.DeathThr = thr
+		}
+		for _, k := range ev.DriveParamNames() {
+			v, _ := ev.DriveParamVal(k)
+			switch {
+			case strings.HasPrefix(k, is+"Cost:"):
+				dr.ActCosts[strings.TrimPrefix(k, is+"Cost:")] = v
+			case strings.HasPrefix(k, is+"Consume:"):
+				dr.ConsumeCosts[strings.TrimPrefix(k, is+"Consume:")] = v
+			}
+		}
+	}
+}
+
+// DriveParam returns the Params value for given drive Inters name and key
+// (e.g., Energy, Decay for EnergyDecay), or def if not present
+func (ev *FWorld) DriveParam(inter, key string, def float32) float32 {
+	if v, ok := ev.DriveParamVal(inter + key); ok {
+		return v
+	}
+	return def
+}
+
+// DriveParamVal returns the Params value for given per-drive key, or for
+// the alias in DriveParamAliases that maps onto it, and whether either is set
+func (ev *FWorld) DriveParamVal(key string) (float32, bool) {
+	if v, ok := ev.Params[key]; ok {
+		return v, true
+	}
+	for al, k := range DriveParamAliases {
+		if k != key {
+			continue
+		}
+		if v, ok := ev.Params[al]; ok {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// DriveParamNames returns the names of all the Params, with the aliases
+// in DriveParamAliases replaced by the per-drive keys they map onto
+func (ev *FWorld) DriveParamNames() []string {
+	nms := make([]string, 0, len(ev.Params))
+	for k := range ev.Params {
+		if pk, ok := DriveParamAliases[k]; ok {
+			if _, has := ev.Params[pk]; has {
+				continue
+			}
+			k = pk
+		}
+		nms = append(nms, k)
+	}
+	return nms
+}
+
+// IsDriveParam returns true if given Params key is a per-drive param
+// for one of the Inters (see DriveParamKeys), or one of the DriveParamAliases
+func (ev *FWorld) IsDriveParam(key string) bool {
+	if _, ok := DriveParamAliases[key]; ok {
+		return true
+	}
+	for _, is := range ev.Inters {
+		if !strings.HasPrefix(key, is) {
+			continue
+		}
+		rest := strings.TrimPrefix(key, is)
+		if strings.HasPrefix(rest, "Cost:") || strings.HasPrefix(rest, "Consume:") {
+			return true
+		}
+		for _, k := range DriveParamKeys {
+			if rest == k {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DriveMatFor returns the material in DriveMats that replenishes the drive
+// for given Inters state, and its DriveMat, or nil if none -- if there are
+// several, the first by name is used
+func (ev *FWorld) DriveMatFor(inter string) (string, *DriveMat) {
+	mats := make([]string, 0, len(ev.DriveMats))
+	for m := range ev.DriveMats {
+		mats = append(mats, m)
+	}
+	sort.Strings(mats)
+	for _, m := range mats {
+		if dm := ev.DriveMats[m]; dm.Inter == inter {
+			return m, dm
+		}
+	}
+	return "", nil
+}
+
+// ConfigDrivesImpl configures the Urgency state for the current agent
+func (ev *FWorld) ConfigDrivesImpl() {
+	us := &etensor.Float32{}
+	us.SetShape([]int{1, len(ev.Drives), ev.PopSize, 1}, nil, []string{"1", "Drives", "Pop", "1"})
+	ev.NextStates["Urgency"] = us
+}
+
+// InitDrives initializes all the interoceptive states for the current
+// agent to 0, and then the drives to their Init levels
+func (ev *FWorld) InitDrives() {
+	for _, is := range ev.Inters {
+		ev.InterStates[is] = 0
+	}
+	for _, dr := range ev.Drives {
+		ev.InterStates[dr.Inter] = dr.Init
+	}
+}
+
+// Urgency returns the urgency of given drive for the current agent
+func (ev *FWorld) Urgency(dr *Drive) float32 {
+	return mat32.Pow(1-ev.InterStates[dr.Inter], dr.UrgPow)
+}
+
+// DriveUrgency returns the urgency of the drive for given Inters state,
+// for the current agent, or 0 if not a drive
+func (ev *FWorld) DriveUrgency(inter string) float32 {
+	for _, dr := range ev.Drives {
+		if dr.Inter == inter {
+			return ev.Urgency(dr)
+		}
+	}
+	return 0
+}
+
+// DriveForMat returns the drive that is replenished by given material, or nil
+func (ev *FWorld) DriveForMat(mat string) *Drive {
+	for _, dr := range ev.Drives {
+		if dr.Mat != "" && dr.Mat == mat {
+			return dr
+		}
+	}
+	return nil
+}
+
+// RefreshTime returns the number of ticks after consumption when the Mat
+// of given drive is refreshed, at the current time of day
+func (ev *FWorld) RefreshTime(dr *Drive) int {
+	if dr.NightRefresh > 0 && ev.IsNight() {
+		return dr.NightRefresh
+	}
+	return dr.Refresh
+}
+
+// DecayDrives does the effects of the passage of time on the drives,
+// and resets the one-tick reward signals
+func (ev *FWorld) DecayDrives() {
+	for _, dr := range ev.Drives {
+		ev.IncState(dr.Inter, -dr.Decay)
+		if dr.RewInter != "" {
+			ev.InterStates[dr.RewInter] = 0
+		}
+	}
+}
+
+// DriveCosts applies the costs of given action to the drives, given the
+// amount of movement (1 = one step), rotation (1 = AngInc), and bumping
+func (ev *FWorld) DriveCosts(as string, mvf, rotf float32, bump bool) {
+	for _, dr := range ev.Drives {
+		cost := dr.MoveCost*mvf + dr.RotCost*rotf + dr.ActCosts[as]
+		if bump {
+			cost += dr.BumpCost
+		}
+		ev.IncState(dr.Inter, -cost)
+	}
+}
+
+// Consume consumes the material in front of the current agent if given
+// action consumes it, for any drive, returning true if so
+func (ev *FWorld) Consume(act int, as string) bool {
+	frmat := ev.ProxMats[0]
+	dr := ev.DriveForMat(ev.MatName(frmat))
+	if dr == nil || dr.Act != as {
+		return false
+	}
+	sat := mat32.Pow(1-ev.InterStates[dr.Inter], dr.SatPow)
+	ev.IncState(dr.Inter, dr.Val*sat)
+	for k, c := range dr.ConsumeCosts {
+		ev.IncState(k, -c)
+	}
+	if dr.RewInter != "" {
+		ev.InterStates[dr.RewInter] = sat
+	}
+	if dr.UsedMat != "" {
+		ev.AddNewEventRefresh(ev.NewEvent(act, frmat, ev.ProxPos[0]))
+		ev.ChangeWorld(ev.ProxPos[0], ev.MatMap[dr.UsedMat])
+	}
+	ev.Event.Set(0)
+	ev.Scene.Incr()
+	ev.Consumed = dr.Mat
+	ev.EpisodeConsumed(dr.Mat)
+	return true
+}
+
+// CheckDeath checks whether any Lethal drive of the current agent is at
+// or below its DeathThr, in which case the agent dies: Dead is set,
+// Deaths is incremented, and the drives are reset to their Init levels.
+func (ev *FWorld) CheckDeath() bool {
+	for _, dr := range ev.Drives {
+		if dr.Lethal && ev.InterStates[dr.Inter] <= dr.DeathThr {
+			ev.Dead = true
+			ev.Deaths++
+			ev.InitDrives()
+			return true
+		}
+	}
+	return false
+}
+
+// RenderUrgency renders the urgency of each drive
+func (ev *FWorld) RenderUrgency() {
+	us := ev.NextStates["Urgency"]
+	for i, dr := range ev.Drives {
+		sv := us.SubSpace([]int{0, i}).(*etensor.Float32)
+		ev.PopCode.Encode(&sv.Values, ev.Urgency(dr), ev.PopSize, false)
+	}
+}
@@ -0,0 +1,304 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/emer/emergent/erand"
+	"github.com/emer/emergent/evec"
+	"github.com/goki/ki/ints"
+	"github.com/goki/ki/kit"
+	"github.com/goki/mat32"
+)
+
+//go:generate stringer -type=EntityPolicies
+
+// EntityPolicies are the behavior policies for moving entities
+type EntityPolicies int32
+
+const (
+	// Wander moves forward, turning randomly and turning away from obstacles
+	Wander EntityPolicies = iota
+
+	// Flee moves away from the nearest agent when within SenseDist, otherwise Wander
+	Flee
+
+	// Chase moves toward the nearest agent when within SenseDist, otherwise Wander
+	Chase
+
+	// Patrol moves along the Path points in order, looping back to the start
+	Patrol
+
+	EntityPoliciesN
+)
+
+var KiT_EntityPolicies = kit.Enums.AddEnum(EntityPoliciesN, kit.NotBitFlag, nil)
+
+func (ev EntityPolicies) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *EntityPolicies) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+
+// Entity is a moving thing in the world, e.g., prey or a predator,
+// which is painted into the World grid using its material, so that
+// it is visible in the depth and fovea views, and which moves according
+// to its behavior Policy.  Entities only move into Empty cells.
+type Entity struct {
+
+	// name of entity
+	Name string `desc:"name of entity"`
+
+	// material used to render the entity in the world -- must be in Mats
+	Mat string `desc:"material used to render the entity in the world -- must be in Mats"`
+
+	// behavior policy
+	Policy EntityPolicies `desc:"behavior policy"`
+
+	// probability of moving on each tick -- 1 = moves as fast as the agent can
+	MoveP float32 `desc:"probability of moving on each tick -- 1 = moves as fast as the agent can"`
+
+	// probability of making a random turn on each tick when wandering
+	TurnP float32 `desc:"probability of making a random turn on each tick when wandering"`
+
+	// [viewif: Policy=Flee,Chase] distance within which the agent is sensed, for Flee and Chase
+	SenseDist float32 `viewif:"Policy=Flee,Chase" desc:"distance within which the agent is sensed, for Flee and Chase"`
+
+	// [viewif: Policy=Patrol] points to move through in order, for Patrol -- starts at the first point
+	Path []evec.Vec2i `viewif:"Policy=Patrol" desc:"points to move through in order, for Patrol -- starts at the first point"`
+
+	// increments to interoceptive states of an agent in contact (within one cell), e.g., BumpPain: 1 for harm, FoodRew: 1, Energy: .5 for reward
+	ContactInc map[string]float32 `desc:"increments to interoceptive states of an agent in contact (within one cell), e.g., BumpPain: 1 for harm, FoodRew: 1, Energy: .5 for reward"`
+
+	// remove the entity from the world on contact with the agent, e.g., prey that is caught
+	RemoveOnContact bool `desc:"remove the entity from the world on contact with the agent, e.g., prey that is caught"`
+
+	// [viewif: RemoveOnContact] if > 0, number of ticks after removal when the entity reappears at a random empty location
+	Respawn int `viewif:"RemoveOnContact" desc:"if > 0, number of ticks after removal when the entity reappears at a random empty location"`
+
+	// true if entity is currently in the world
+	Active bool `inactive:"+" desc:"true if entity is currently in the world"`
+
+	// current location, floating point
+	PosF mat32.Vec2 `inactive:"+" desc:"current location, floating point"`
+
+	// current location, integer
+	PosI evec.Vec2i `inactive:"+" desc:"current location, integer"`
+
+	// current heading angle, in degrees
+	Angle int `inactive:"+" desc:"current heading angle, in degrees"`
+
+	// [viewif: Policy=Patrol] index of the next Path point to move toward
+	PathIdx int `viewif:"Policy=Patrol" inactive:"+" desc:"index of the next Path point to move toward"`
+
+	// tick when entity was removed
+	RemoveTick int `inactive:"+" desc:"tick when entity was removed"`
+}
+
+// AddEntity adds a new entity with given name, material and policy,
+// with default parameters that can be further configured on the returned entity.
+func (ev *FWorld) AddEntity(name, mat string, policy EntityPolicies) *Entity {
+	en := &Entity{Name: name, Mat: mat, Policy: policy, MoveP: 1, TurnP: 0.2, SenseDist: 20}
+	en.ContactInc = make(map[string]float32)
+	ev.Entities = append(ev.Entities, en)
+	return en
+}
+
+// ConfigEntities configures an example set of entities: 4 fleeing prey
+// and one chasing predator, adding their materials to Mats if not present.
+// Entities are off by default: call this (or AddEntity) in Config after
+// SetParams, before ConfigPats and ConfigImpl, to use them.
+func (ev *FWorld) ConfigEntities() {
+	ev.Entities = nil
+	for _, m := range []string{"Prey", "Predator"} {
+		if !ev.HasMat(m) {
+			ev.Mats = append(ev.Mats, m)
+		}
+	}
+	for i := 0; i < 4; i++ {
+		en := ev.AddEntity("Prey", "Prey", Flee)
+		en.MoveP = 0.5
+		en.ContactInc["FoodRew"] = 1
+		en.ContactInc["Energy"] = ev.DriveParam("Energy", "Val", 0)
+		en.RemoveOnContact = true
+		en.Respawn = int(ev.DriveParam("Energy", "Refresh", 0))
+	}
+	en := ev.AddEntity("Predator", "Predator", Chase)
+	en.MoveP = 0.5
+	en.ContactInc["BumpPain"] = 1
+	en.ContactInc["Energy"] = -ev.Params["BumpCost"]
+	en.ContactInc["Hydra"] = -ev.Params["BumpCost"]
+}
+
+// HasMat returns true if given material is in Mats
+func (ev *FWorld) HasMat(mat string) bool {
+	for _, m := range ev.Mats {
+		if m == mat {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfigEntitiesImpl does the automatic parts of entity configuration
+func (ev *FWorld) ConfigEntitiesImpl() {
+	ev.EntityMats = make(map[int]bool)
+	for _, en := range ev.Entities {
+		if mi, ok := ev.MatMap[en.Mat]; ok {
+			ev.EntityMats[mi] = true
+		}
+	}
+}
+
+// IsOpaque returns true if given material blocks the depth view:
+// barriers, landmark cue cards, entities and other agents
+func (ev *FWorld) IsOpaque(mat int) bool {
+	return mat > 0 && (mat <= ev.BarrierIdx || ev.LandmarkMats[mat] || ev.EntityMats[mat] || mat == ev.AgentMat)
+}
+
+// InitEntities places all the entities into the world at their starting
+// locations: first Path point for Patrol, else a random empty location
+func (ev *FWorld) InitEntities() {
+	for _, en := range ev.Entities {
+		en.Active = false
+		en.PathIdx = 0
+		if en.Policy == Patrol && len(en.Path) > 0 {
+			ev.PlaceEntity(en, en.Path[0])
+			en.PathIdx = 1 % len(en.Path)
+		} else if p, ok := ev.RandomEmpty(); ok {
+			ev.PlaceEntity(en, p)
+		}
+		en.Angle = ev.Rand.Intn(ev.NRotAngles-1, -1) * ev.AngInc
+	}
+}
+
+// RandomEmpty returns a random empty location in the world, not at any
+// agent position, and true -- or false if there are no such locations.
+// It first tries a limited number of random locations, and then picks
+// at random from all the empty locations.
+func (ev *FWorld) RandomEmpty() (evec.Vec2i, bool) {
+	isEmpty := func(p evec.Vec2i) bool {
+		return ev.GetWorld(p) == 0 && ev.AgentAt(p, nil) == nil
+	}
+	for i := 0; i < 100; i++ {
+		p := evec.Vec2i{ev.Rand.Intn(ev.Size.X, -1), ev.Rand.Intn(ev.Size.Y, -1)}
+		if isEmpty(p) {
+			return p, true
+		}
+	}
+	var emps []evec.Vec2i
+	for y := 0; y < ev.Size.Y; y++ {
+		for x := 0; x < ev.Size.X; x++ {
+			if p := (evec.Vec2i{x, y}); isEmpty(p) {
+				emps = append(emps, p)
+			}
+		}
+	}
+	if len(emps) == 0 {
+		return evec.Vec2i{}, false
+	}
+	return emps[ev.Rand.Intn(len(emps), -1)], true
+}
+
+// PlaceEntity places entity at given position in the world
+func (ev *FWorld) PlaceEntity(en *Entity, p evec.Vec2i) {
+	en.PosI = p
+	en.PosF = p.ToVec2()
+	en.Active = true
+	ev.ChangeWorld(p, ev.MatMap[en.Mat])
+}
+
+// RemoveEntity removes entity from the world
+func (ev *FWorld) RemoveEntity(en *Entity) {
+	ev.ChangeWorld(en.PosI, 0)
+	en.Active = false
+	en.RemoveTick = ev.Tick.Cur
+}
+
+// AngTo returns the angle from one point to another, in degrees,
+// rounded to the nearest AngInc
+func (ev *FWorld) AngTo(from, to mat32.Vec2) int {
+	d := to.Sub(from)
+	ang := mat32.RadToDeg(mat32.Atan2(d.Y, d.X))
+	ai := int(mat32.Round(ang/float32(ev.AngInc))) * ev.AngInc
+	return AngMod(ai)
+}
+
+// UpdateEntities moves all the active entities according to their
+// policies, respawns removed ones, and applies any contact effects
+func (ev *FWorld) UpdateEntities() {
+	for _, en := range ev.Entities {
+		if !en.Active {
+			if en.Respawn > 0 && ev.Tick.Cur-en.RemoveTick >= en.Respawn {
+				if p, ok := ev.RandomEmpty(); ok { // else try again next tick
+					ev.PlaceEntity(en, p)
+				}
+			}
+			continue
+		}
+		if ev.EntityContact(en) {
+			continue
+		}
+		if erand.BoolP32(en.MoveP, -1, &ev.Rand) {
+			ev.MoveEntity(en)
+		}
+		ev.EntityContact(en)
+	}
+}
+
+// EntityContact applies contact effects to each agent within one cell
+// of the entity, returning true if contact happened.  If RemoveOnContact,
+// only the first such agent (in Agents order) gets the contact effects.
+func (ev *FWorld) EntityContact(en *Entity) bool {
+	if !en.Active {
+		return false
+	}
+	contact := false
+	for _, ag := range ev.Agents {
+		d := en.PosI.Sub(ag.PosI)
+		if ints.AbsInt(d.X) > 1 || ints.AbsInt(d.Y) > 1 {
+			continue
+		}
+		for k, inc := range en.ContactInc {
+			ag.IncState(k, inc)
+		}
+		contact = true
+		if en.RemoveOnContact {
+			ev.RemoveEntity(en)
+			break
+		}
+	}
+	return contact
+}
+
+// MoveEntity moves entity according to its policy, relative to the nearest agent
+func (ev *FWorld) MoveEntity(en *Entity) {
+	ag := ev.NearestAgent(en.PosF)
+	dist := en.PosF.DistTo(ag.PosF)
+	switch {
+	case en.Policy == Flee && dist <= en.SenseDist:
+		en.Angle = AngMod(ev.AngTo(en.PosF, ag.PosF) + 180)
+	case en.Policy == Chase && dist <= en.SenseDist:
+		en.Angle = ev.AngTo(en.PosF, ag.PosF)
+	case en.Policy == Patrol && len(en.Path) > 0:
+		if en.PosF.DistTo(en.Path[en.PathIdx].ToVec2()) < 1 {
+			en.PathIdx = (en.PathIdx + 1) % len(en.Path)
+		}
+		en.Angle = ev.AngTo(en.PosF, en.Path[en.PathIdx].ToVec2())
+	default:
+		if erand.BoolP32(en.TurnP, -1, &ev.Rand) {
+			if erand.BoolP(.5, -1, &ev.Rand) {
+				en.Angle = AngMod(en.Angle + ev.AngInc)
+			} else {
+				en.Angle = AngMod(en.Angle - ev.AngInc)
+			}
+		}
+	}
+	nf, np := NextVecPoint(en.PosF, AngVec(en.Angle))
+	if !ev.InWorld(np) || ev.GetWorld(np) != 0 || ev.AgentAt(np, nil) != nil {
+		// blocked: turn in a random direction and try again next time
+		en.Angle = AngMod(en.Angle + (90 + 90*ev.Rand.Intn(3, -1)))
+		return
+	}
+	ev.ChangeWorld(en.PosI, 0)
+	en.PosF, en.PosI = nf, np
+	ev.ChangeWorld(np, ev.MatMap[en.Mat])
+}
@@ -0,0 +1,216 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/emer/emergent/evec"
+	"github.com/goki/ki/kit"
+)
+
+//go:generate stringer -type=ResetModes
+
+// ResetModes are the ways of resetting the world at the start of a new episode
+type ResetModes int32
+
+const (
+	// ResetKeep keeps the world and agent positions as they are, only resetting the drives
+	ResetKeep ResetModes = iota
+
+	// ResetRespawn keeps the world as it is, and respawns the agents at random empty cells
+	ResetRespawn
+
+	// ResetRegen regenerates the world with GenWorld using a new random seed,
+	// and respawns the agents and entities at random empty cells
+	ResetRegen
+
+	ResetModesN
+)
+
+var KiT_ResetModes = kit.Enums.AddEnum(ResetModesN, kit.NotBitFlag, nil)
+
+func (ev ResetModes) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *ResetModes) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+
+//go:generate stringer -type=TermReasons
+
+// TermReasons are the reasons why an episode terminated
+type TermReasons int32
+
+const (
+	// TermNone means the episode has not terminated
+	TermNone TermReasons = iota
+
+	// TermDrive means a drive level of some agent reached zero, or it died
+	TermDrive
+
+	// TermSteps means the episode used up its step budget
+	TermSteps
+
+	// TermGoal means some agent reached the goal material
+	TermGoal
+
+	// TermConsume means the agents consumed the target number of items
+	TermConsume
+
+	TermReasonsN
+)
+
+var KiT_TermReasons = kit.Enums.AddEnum(TermReasonsN, kit.NotBitFlag, nil)
+
+func (ev TermReasons) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *TermReasons) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+
+// EpisodeTerm has the conditions for terminating an episode, and how to
+// reset the world for the next one.  All conditions are checked after each
+// action, and any that is met terminates the episode.
+type EpisodeTerm struct {
+
+	// terminate when any drive level of any agent reaches zero, or an agent dies from a Lethal drive
+	DriveZero bool `desc:"terminate when any drive level of any agent reaches zero, or an agent dies from a Lethal drive"`
+
+	// terminate after this many action steps -- 0 = no limit
+	MaxSteps int `desc:"terminate after this many action steps -- 0 = no limit"`
+
+	// terminate when any agent reaches this material, i.e., it is directly in front, or was consumed -- empty = none
+	GoalMat string `desc:"terminate when any agent reaches this material, i.e., it is directly in front, or was consumed -- empty = none"`
+
+	// terminate after the agents have consumed this many items in total -- 0 = no limit
+	MaxConsume int `desc:"terminate after the agents have consumed this many items in total -- 0 = no limit"`
+
+	// how to reset the world at the start of the next episode
+	Reset ResetModes `desc:"how to reset the world at the start of the next episode"`
+}
+
+// EpisodeState is the state of the current episode
+type EpisodeState struct {
+
+	// number of action steps taken in this episode
+	Steps int `desc:"number of action steps taken in this episode"`
+
+	// number of items consumed by all agents in this episode
+	Consumed int `desc:"number of items consumed by all agents in this episode"`
+
+	// true if the GoalMat was consumed on the last action
+	GoalConsumed bool `desc:"true if the GoalMat was consumed on the last action"`
+
+	// reason the episode terminated on the last action, or TermNone -- the next Step starts a new episode
+	Term TermReasons `desc:"reason the episode terminated on the last action, or TermNone -- the next Step starts a new episode"`
+}
+
+// Done returns true if the episode has terminated
+func (es *EpisodeState) Done() bool {
+	return es.Term != TermNone
+}
+
+// Init initializes the state for a new episode
+func (es *EpisodeState) Init() {
+	es.Steps = 0
+	es.Consumed = 0
+	es.GoalConsumed = false
+	es.Term = TermNone
+}
+
+// EpisodeConsumed records the consumption of given material
+// for the termination conditions
+func (ev *FWorld) EpisodeConsumed(mat string) {
+	ev.Ep.Consumed++
+	if mat == ev.Term.GoalMat {
+		ev.Ep.GoalConsumed = true
+	}
+}
+
+// CheckEpisode counts an action step and checks the termination
+// conditions, setting Ep.Term if the episode is done.  It is called
+// after the actions of all agents have been taken and rendered.
+func (ev *FWorld) CheckEpisode() TermReasons {
+	ev.Ep.Steps++
+	ev.Ep.Term = ev.TermReason()
+	ev.Ep.GoalConsumed = false
+	return ev.Ep.Term
+}
+
+// TermReason returns the first termination condition that is met
+// in the current state, or TermNone
+func (ev *FWorld) TermReason() TermReasons {
+	tm := &ev.Term
+	if tm.DriveZero {
+		for _, ag := range ev.Agents {
+			if ag.Dead {
+				return TermDrive
+			}
+			for _, dr := range ev.Drives {
+				if ag.InterStates[dr.Inter] <= 0 {
+					return TermDrive
+				}
+			}
+		}
+	}
+	if tm.GoalMat != "" {
+		if ev.Ep.GoalConsumed {
+			return TermGoal
+		}
+		if gm, ok := ev.MatMap[tm.GoalMat]; ok {
+			for _, ag := range ev.Agents {
+				if ag.ProxMats[0] == gm || ev.GetWorld(ag.PosI) == gm {
+					return TermGoal
+				}
+			}
+		}
+	}
+	if tm.MaxConsume > 0 && ev.Ep.Consumed >= tm.MaxConsume {
+		return TermConsume
+	}
+	if tm.MaxSteps > 0 && ev.Ep.Steps >= tm.MaxSteps {
+		return TermSteps
+	}
+	return TermNone
+}
+
+// NewEpisode starts a new episode, resetting the world according to
+// Term.Reset, and the drives of all agents.  The Episode counter is
+// incremented, and the Scene and Event counters start over.
+// This is called by Step after an episode has terminated, and can also
+// be called directly to end the current episode at any point.
+func (ev *FWorld) NewEpisode() {
+	switch ev.Term.Reset {
+	case ResetRespawn:
+		ev.RespawnAgents()
+	case ResetRegen:
+		ev.SeedRand(ev.Rand.Int63(-1))
+		ev.GenWorld()
+		ev.InitLandmarks()
+		ev.RefreshEvents = make(map[int]*WEvent)
+		ev.RespawnAgents()
+		ev.InitEntities()
+		ev.InitOdors()
+	default:
+		ev.AgentsDo(func(ag *Agent) {
+			ag.Dead = false
+			ev.InitDrives()
+		})
+	}
+	ev.LandmarksNewEpisode()
+	ev.AgentsDo(func(ag *Agent) {
+		ev.ScanState()
+	})
+	ev.Ep.Init()
+	ev.Episode.Incr()
+	ev.Scene.Set(0)
+	ev.Event.Cur = -1 // next Step = 0
+}
+
+// RespawnAgents moves all the agents to random empty cells, and
+// re-initializes their pose and drives, starting on the next Step
+func (ev *FWorld) RespawnAgents() {
+	ev.AgentsDo(func(ag *Agent) {
+		prv := ag.PosI
+		ag.PosI = evec.Vec2i{-1, -1} // not blocking its own spawn
+		p, ok := ev.RandomEmpty()
+		if !ok { // stay in place
+			p = prv
+		}
+		ev.InitAgent(p)
+	})
+}
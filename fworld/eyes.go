@@ -0,0 +1,114 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/emer/etable/etensor"
+)
+
+// Eye is one eye in a multi-eye visual system, e.g., two side-facing
+// rodent eyes, each with its own heading offset and field of view,
+// rendered into its own Depth, FovDepth and Fovea states.
+type Eye struct {
+
+	// name of eye -- appended to state names, e.g., DepthLeft
+	Name string `desc:"name of eye -- appended to state names, e.g., DepthLeft"`
+
+	// heading offset of the center of the eye's view relative to body heading, in degrees -- positive = left
+	Offset int `desc:"heading offset of the center of the eye's view relative to body heading, in degrees -- positive = left"`
+
+	// field of view in degrees, must be even multiple of AngInc
+	FOV int `desc:"field of view in degrees, must be even multiple of AngInc"`
+
+	// total number of FOV rays that are traced
+	NFOVRays int `inactive:"+" desc:"total number of FOV rays that are traced"`
+
+	// depth for each angle (NFOVRays), raw
+	Depths []float32 `desc:"depth for each angle (NFOVRays), raw"`
+
+	// depth for each angle (NFOVRays), normalized log
+	DepthLogs []float32 `desc:"depth for each angle (NFOVRays), normalized log"`
+
+	// material at each angle
+	ViewMats []int `inactive:"+" desc:"material at each angle"`
+
+	// materials at fovea, L-R
+	FovMats []int `desc:"materials at fovea, L-R"`
+
+	// raw depths to foveal materials, L-R
+	FovDepths []float32 `desc:"raw depths to foveal materials, L-R"`
+
+	// normalized log depths to foveal materials, L-R
+	FovDepthLogs []float32 `desc:"normalized log depths to foveal materials, L-R"`
+}
+
+// SetEyes configures n eyes with given field of view, arranged symmetrically
+// around the heading, with adjacent eyes overlapping by given binocular overlap,
+// in degrees.  For example, SetEyes(2, 180, 40) gives two side-facing
+// rodent-style eyes with 320 degrees of total coverage.  Eyes are ordered L-R.
+// Must call ConfigImpl after this to configure the states.
+func (ev *FWorld) SetEyes(n, fov, overlap int) {
+	ev.BinocOverlap = overlap
+	ev.Eyes = make([]*Eye, n)
+	sep := fov - overlap
+	for i := 0; i < n; i++ {
+		ey := &Eye{FOV: fov}
+		ey.Offset = ((n-1)*sep)/2 - i*sep
+		switch {
+		case n == 2 && i == 0:
+			ey.Name = "Left"
+		case n == 2:
+			ey.Name = "Right"
+		default:
+			ey.Name = fmt.Sprintf("Eye%d", i)
+		}
+		ev.Eyes[i] = ey
+	}
+}
+
+// ConfigEyesImpl does the automatic parts of configuring the eyes
+func (ev *FWorld) ConfigEyesImpl() {
+	fsz := 1 + 2*ev.FoveaSize
+	for _, ey := range ev.Eyes {
+		ey.NFOVRays = (ey.FOV / ev.AngInc) + 1
+		ey.Depths = make([]float32, ey.NFOVRays)
+		ey.DepthLogs = make([]float32, ey.NFOVRays)
+		ey.ViewMats = make([]int, ey.NFOVRays)
+		ey.FovMats = make([]int, fsz)
+		ey.FovDepths = make([]float32, fsz)
+		ey.FovDepthLogs = make([]float32, fsz)
+
+		dv := &etensor.Float32{}
+		dv.SetShape([]int{1, ey.NFOVRays, ev.PopSize, 1}, nil, []string{"1", "Angle", "Pop", "1"})
+		ev.NextStates["Depth"+ey.Name] = dv
+
+		fd := &etensor.Float32{}
+		fd.SetShape([]int{1, fsz, ev.PopSize, 1}, nil, []string{"1", "Angle", "Pop", "1"})
+		ev.NextStates["FovDepth"+ey.Name] = fd
+
+		fv := &etensor.Float32{}
+		fv.SetShape([]int{1, fsz, ev.PatSize.Y, ev.PatSize.X}, nil, []string{"1", "Angle", "Y", "X"})
+		ev.NextStates["Fovea"+ey.Name] = fv
+	}
+}
+
+// ScanEyes scans depth and fovea for each eye
+func (ev *FWorld) ScanEyes() {
+	for _, ey := range ev.Eyes {
+		ang := ev.Angle + ey.Offset
+		ev.ScanDepthFOV(ev.PosF, ang, ey.FOV, ey.Depths, ey.DepthLogs, ey.ViewMats)
+		ev.ScanFoveaAng(ev.PosF, ang, ey.FovDepths, ey.FovDepthLogs, ey.FovMats)
+	}
+}
+
+// RenderEyes renders the view state for each eye
+func (ev *FWorld) RenderEyes() {
+	for _, ey := range ev.Eyes {
+		ev.RenderDepth(ev.NextStates["Depth"+ey.Name], ey.DepthLogs)
+		ev.RenderFovea(ev.NextStates["FovDepth"+ey.Name], ev.NextStates["Fovea"+ey.Name], ey.FovDepths, ey.FovDepthLogs, ey.FovMats)
+	}
+}
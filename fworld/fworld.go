@@ -53,6 +53,9 @@ type FWorld struct {
 	// index of material below which (inclusive) cannot move -- e.g., 1 for wall
 	BarrierIdx int `desc:"index of material below which (inclusive) cannot move -- e.g., 1 for wall"`
 
+	// parameters for generating patterns for materials and actions that are not in the pats.json file
+	PatGen PatGenParams `desc:"parameters for generating patterns for materials and actions that are not in the pats.json file"`
+
 	// patterns for each material (must include Empty) and for each action
 	Pats map[string]*etensor.Float32 `desc:"patterns for each material (must include Empty) and for each action"`
 
@@ -71,24 +74,54 @@ type FWorld struct {
 	// map of optional interoceptive and world-dynamic parameters -- cleaner to store in a map
 	Params map[string]float32 `desc:"map of optional interoceptive and world-dynamic parameters -- cleaner to store in a map"`
 
+	// parameter values that override the defaults set in Config, e.g., for batch parameter searches -- applied by SetParams in Config, before the drives are configured from the Params
+	ParamSet map[string]float32 `desc:"parameter values that override the defaults set in Config, e.g., for batch parameter searches -- applied by SetParams in Config, before the drives are configured from the Params"`
+
+	// [view: -] default Params set in ConfigDefaults, which SetParams starts from
+	DefParams map[string]float32 `view:"-" desc:"default Params set in ConfigDefaults, which SetParams starts from"`
+
+	// generate the world with GenWorld in Init, instead of loading it from world.tsv -- e.g., for headless runs, which write no files
+	GenWorldInit bool `desc:"generate the world with GenWorld in Init, instead of loading it from world.tsv -- e.g., for headless runs, which write no files"`
+
+	// interoceptive drives, each with its own dynamics of decay, costs and consumption -- configured by default from Params in ConfigDrives
+	Drives []*Drive `desc:"interoceptive drives, each with its own dynamics of decay, costs and consumption -- configured by default from Params in ConfigDrives"`
+
+	// drive replenished by each material when consumed, keyed by material name -- used with the per-drive Params in ConfigDrives
+	DriveMats map[string]*DriveMat `desc:"drive replenished by each material when consumed, keyed by material name -- used with the per-drive Params in ConfigDrives"`
+
 	// field of view in degrees, e.g., 180, must be even multiple of AngInc
 	FOV int `desc:"field of view in degrees, e.g., 180, must be even multiple of AngInc"`
 
 	// angle increment for rotation, in degrees -- defaults to 15
 	AngInc int `desc:"angle increment for rotation, in degrees -- defaults to 15"`
 
+	// use continuous kinematic locomotion: actions set linear and angular velocity, with sub-cell positions and any heading angle, circle-vs-grid collisions, and Vestibular encoding angular and linear acceleration.  Must call ConfigImpl after changing
+	Continuous bool `desc:"use continuous kinematic locomotion: actions set linear and angular velocity, with sub-cell positions and any heading angle, circle-vs-grid collisions, and Vestibular encoding angular and linear acceleration.  Must call ConfigImpl after changing"`
+
 	// total number of rotation angles in a circle
 	NRotAngles int `inactive:"+" desc:"total number of rotation angles in a circle"`
 
 	// total number of FOV rays that are traced
 	NFOVRays int `inactive:"+" desc:"total number of FOV rays that are traced"`
 
+	// optional additional eyes, each with its own heading offset and FOV, rendered into separate Depth, FovDepth, Fovea states with eye name appended -- empty = only the single forward FOV view (primate-like).  see SetEyes
+	Eyes []*Eye `desc:"optional additional eyes, each with its own heading offset and FOV, rendered into separate Depth, FovDepth, Fovea states with eye name appended -- empty = only the single forward FOV view (primate-like).  see SetEyes"`
+
+	// binocular overlap between adjacent eyes, in degrees, as set by SetEyes
+	BinocOverlap int `inactive:"+" desc:"binocular overlap between adjacent eyes, in degrees, as set by SetEyes"`
+
 	// for debugging only: show the main depth rays as they are traced out from point
 	ShowRays bool `desc:"for debugging only: show the main depth rays as they are traced out from point"`
 
 	// for debugging only: show the fovea rays as they are traced out from point
 	ShowFovRays bool `desc:"for debugging only: show the fovea rays as they are traced out from point"`
 
+	// ActGen follows the optimal path to the most needed consumable (OracleAct), instead of using heuristics -- for supervised targets and scoring efficiency
+	Oracle bool `desc:"ActGen follows the optimal path to the most needed consumable (OracleAct), instead of using heuristics -- for supervised targets and scoring efficiency"`
+
+	// [view: -] last plan from PlanTo for each agent, by ID, which is reused until the world changes or the agent leaves the planned path
+	Plans map[string]*PlanCache `view:"-" desc:"last plan from PlanTo for each agent, by ID, which is reused until the world changes or the agent leaves the planned path"`
+
 	// for debugging, print out a trace of the action generation logic
 	TraceActGen bool `desc:"for debugging, print out a trace of the action generation logic"`
 
@@ -104,59 +137,62 @@ type FWorld struct {
 	// population code values, in normalized units
 	PopCode popcode.OneD `desc:"population code values, in normalized units"`
 
-	// current location of agent, floating point
-	PosF mat32.Vec2 `inactive:"+" desc:"current location of agent, floating point"`
+	// angle population code values, in normalized units, which wrap around at the ends -- used for landmark bearings
+	AngCode popcode.Ring `desc:"angle population code values, in normalized units, which wrap around at the ends -- used for landmark bearings"`
+
+	// current agent, whose state fields (PosF, InterStates, CurStates etc) are accessed directly through the FWorld -- see SetAgent
+	*Agent `desc:"current agent, whose state fields (PosF, InterStates, CurStates etc) are accessed directly through the FWorld -- see SetAgent"`
 
-	// current location of agent, integer
-	PosI evec.Vec2i `inactive:"+" desc:"current location of agent, integer"`
+	// all the agents in the world, in the order in which they act -- the first is the default current agent.  see AddAgent
+	Agents []*Agent `desc:"all the agents in the world, in the order in which they act -- the first is the default current agent.  see AddAgent"`
 
-	// current angle, in degrees
-	Angle int `inactive:"+" desc:"current angle, in degrees"`
+	// map of agent ID to index in Agents
+	AgentMap map[string]int `desc:"map of agent ID to index in Agents"`
 
-	// angle that we just rotated -- drives vestibular
-	RotAng int `inactive:"+" desc:"angle that we just rotated -- drives vestibular"`
+	// index of the current agent in Agents
+	CurAgent int `inactive:"+" desc:"index of the current agent in Agents"`
 
-	// last action taken
-	Act int `inactive:"+" desc:"last action taken"`
+	// material index that other agents appear as in the views, from the Agent material -- 0 if not present in Mats
+	AgentMat int `inactive:"+" desc:"material index that other agents appear as in the views, from the Agent material -- 0 if not present in Mats"`
 
-	// depth for each angle (NFOVRays), raw
-	Depths []float32 `desc:"depth for each angle (NFOVRays), raw"`
+	// randomly shuffle the order in which agents act on each ActionAgents call, using Rand -- otherwise they always act in the order of Agents
+	ShuffleAgents bool `desc:"randomly shuffle the order in which agents act on each ActionAgents call, using Rand -- otherwise they always act in the order of Agents"`
 
-	// depth for each angle (NFOVRays), normalized log
-	DepthLogs []float32 `desc:"depth for each angle (NFOVRays), normalized log"`
+	// list of odor types, each of which has its own diffused odor field, emitted by materials according to MatOdors
+	Odors []string `desc:"list of odor types, each of which has its own diffused odor field, emitted by materials according to MatOdors"`
 
-	// material at each angle
-	ViewMats []int `inactive:"+" desc:"material at each angle"`
+	// map of odor type names to indexes
+	OdorMap map[string]int `desc:"map of odor type names to indexes"`
 
-	// materials at fovea, L-R
-	FovMats []int `desc:"materials at fovea, L-R"`
+	// odor emitted by each material, keyed by material name -- materials not in the map do not emit odor
+	MatOdors map[string]*MatOdor `desc:"odor emitted by each material, keyed by material name -- materials not in the map do not emit odor"`
 
-	// raw depths to foveal materials, L-R
-	FovDepths []float32 `desc:"raw depths to foveal materials, L-R"`
+	// [view: no-inline] diffused odor concentration field for each odor type, same shape as World
+	OdorFields map[string]*etensor.Float32 `view:"no-inline" desc:"diffused odor concentration field for each odor type, same shape as World"`
 
-	// normalized log depths to foveal materials, L-R
-	FovDepthLogs []float32 `desc:"normalized log depths to foveal materials, L-R"`
+	// moving entities in the world, e.g., prey and predators, which are painted into the World using their material
+	Entities []*Entity `desc:"moving entities in the world, e.g., prey and predators, which are painted into the World using their material"`
 
-	// material at each right angle: front, left, right back
-	ProxMats []int `desc:"material at each right angle: front, left, right back"`
+	// materials used by entities -- these block the depth view like barriers
+	EntityMats map[int]bool `inactive:"+" desc:"materials used by entities -- these block the depth view like barriers"`
 
-	// coordinates for proximal grid points: front, left, right, back
-	ProxPos []evec.Vec2i `desc:"coordinates for proximal grid points: front, left, right, back"`
+	// landmarks for allocentric navigation: cue cards painted onto the walls, and distal cues beyond the walls, seen in the Landmarks view
+	Landmarks []*Landmark `desc:"landmarks for allocentric navigation: cue cards painted onto the walls, and distal cues beyond the walls, seen in the Landmarks view"`
 
-	// floating point value of internal states -- dim of Inters
-	InterStates map[string]float32 `inactive:"+" desc:"floating point value of internal states -- dim of Inters"`
+	// materials used by landmark cue cards -- these block movement and the depth view like barriers
+	LandmarkMats map[int]bool `inactive:"+" desc:"materials used by landmark cue cards -- these block movement and the depth view like barriers"`
 
-	// current rendered state tensors -- extensible map
-	CurStates map[string]*etensor.Float32 `desc:"current rendered state tensors -- extensible map"`
+	// current rotation of the landmarks from their Home positions, in degrees
+	LandmarkRot float32 `inactive:"+" desc:"current rotation of the landmarks from their Home positions, in degrees"`
 
-	// next rendered state tensors -- updated from actions
-	NextStates map[string]*etensor.Float32 `desc:"next rendered state tensors -- updated from actions"`
+	// [view: inline] changes made to the landmarks at the start of each new episode, e.g., cue rotation
+	LandmarkEp LandmarkEpisode `view:"inline" desc:"changes made to the landmarks at the start of each new episode, e.g., cue rotation"`
 
-	// list of events, key is tick step, to check each step to drive refresh of consumables -- removed from this active list when complete
-	RefreshEvents map[int]*WEvent `desc:"list of events, key is tick step, to check each step to drive refresh of consumables -- removed from this active list when complete"`
+	// list of events, key is event index in AllEvents, to check each step to drive refresh of consumables -- removed from this active list when complete
+	RefreshEvents map[int]*WEvent `desc:"list of events, key is event index in AllEvents, to check each step to drive refresh of consumables -- removed from this active list when complete"`
 
-	// list of all events, key is tick step
-	AllEvents map[int]*WEvent `desc:"list of all events, key is tick step"`
+	// list of all events, key is event index, in order of occurrence -- multiple agents can have events on the same tick
+	AllEvents map[int]*WEvent `desc:"list of all events, key is event index, in order of occurrence -- multiple agents can have events on the same tick"`
 
 	// [view: inline] current run of model as provided during Init
 	Run env.Ctr `view:"inline" desc:"current run of model as provided during Init"`
@@ -178,6 +214,42 @@ type FWorld struct {
 
 	// [view: arbitrary counter incrementing over scenes within larger episode: feeding, drinking, exploring, etc]
 	Episode env.Ctr `view:"arbitrary counter incrementing over scenes within larger episode: feeding, drinking, exploring, etc"`
+
+	// [view: inline] conditions for terminating an episode, and how to reset the world for the next one
+	Term EpisodeTerm `view:"inline" desc:"conditions for terminating an episode, and how to reset the world for the next one"`
+
+	// [view: inline] state of the current episode
+	Ep EpisodeState `view:"inline" desc:"state of the current episode"`
+
+	// [view: inline] noise in the sensory states, applied when rendering them
+	Noise SensorNoise `view:"inline" desc:"noise in the sensory states, applied when rendering them"`
+
+	// phase of the day-night cycle (0-1), starting at noon = 0, with midnight = .5 -- see DayLen param
+	DayPhase float32 `inactive:"+" desc:"phase of the day-night cycle (0-1), starting at noon = 0, with midnight = .5 -- see DayLen param"`
+
+	// current light level (0-1), from the DayPhase -- it is night when < .5
+	Light float32 `inactive:"+" desc:"current light level (0-1), from the DayPhase -- it is night when < .5"`
+
+	// current visual range in cells, from the Light -- 0 = unlimited
+	VisRange float32 `inactive:"+" desc:"current visual range in cells, from the Light -- 0 = unlimited"`
+
+	// current contrast of fovea patterns, from the Light
+	Contrast float32 `inactive:"+" desc:"current contrast of fovea patterns, from the Light"`
+
+	// random seed for the Rand random number generator, used for all stochastic world dynamics (but not ActGen) -- set at Init and StartRecord
+	RndSeed int64 `desc:"random seed for the Rand random number generator, used for all stochastic world dynamics (but not ActGen) -- set at Init and StartRecord"`
+
+	// [view: -] random number generator for the world, seeded from RndSeed by SeedRand
+	Rand erand.SysRand `view:"-" desc:"random number generator for the world, seeded from RndSeed by SeedRand"`
+
+	// [view: -] source for Rand, which counts the draws so that Snapshot can save the position in the random sequence
+	RandSrc *RandSrc `view:"-" desc:"source for Rand, which counts the draws so that Snapshot can save the position in the random sequence"`
+
+	// true if currently recording actions into Rec
+	Recording bool `inactive:"+" desc:"true if currently recording actions into Rec"`
+
+	// [view: no-inline] current episode record: starting snapshot and all actions since StartRecord
+	Rec *EpisodeRec `view:"no-inline" desc:"current episode record: starting snapshot and all actions since StartRecord"`
 }
 
 var KiT_FWorld = kit.Types.AddType(&FWorld{}, FWorldProps)
@@ -185,13 +257,31 @@ var KiT_FWorld = kit.Types.AddType(&FWorld{}, FWorldProps)
 func (ev *FWorld) Name() string { return ev.Nm }
 func (ev *FWorld) Desc() string { return ev.Dsc }
 
-// Config configures the world
+// Config configures the world, with the ParamSet applied to the default Params
 func (ev *FWorld) Config(ntrls int) {
+	ev.ConfigDefaults(ntrls)
+	if err := ev.SetParams(ev.ParamSet); err != nil {
+		fmt.Println(err)
+	}
+	// ev.ConfigEntities() // uncomment for prey and predator entities
+	if err := ev.ConfigPats(); err != nil {
+		fmt.Println(err)
+	}
+	ev.ConfigImpl()
+
+	// uncomment to generate a new world
+	ev.GenWorld()
+	ev.SaveWorld("world.tsv")
+}
+
+// ConfigDefaults sets the default configuration and Params (saved in
+// DefParams), prior to SetParams, ConfigPats and ConfigImpl
+func (ev *FWorld) ConfigDefaults(ntrls int) {
 	ev.Nm = "Demo"
 	ev.Dsc = "Example world with basic food / water / eat / drink actions"
-	ev.Mats = []string{"Empty", "Wall", "Food", "Water", "FoodWas", "WaterWas"}
+	ev.Mats = []string{"Empty", "Wall", "Food", "Water", "FoodWas", "WaterWas", "Agent"}
 	ev.BarrierIdx = 1
-	ev.Acts = []string{"Stay", "Left", "Right", "Forward", "Backward", "Eat", "Drink"}
+	ev.Acts = []string{"Stay", "Left", "Right", "Forward", "Backward", "Eat", "Drink", "Move"}
 	ev.Inters = []string{"Energy", "Hydra", "BumpPain", "FoodRew", "WaterRew"}
 
 	ev.Params = make(map[string]float32)
@@ -200,52 +290,129 @@ func (ev *FWorld) Config(ntrls int) {
 	ev.Params["MoveCost"] = 0.002  // additional decrement due to moving
 	ev.Params["RotCost"] = 0.001   // additional decrement due to rotating one step
 	ev.Params["BumpCost"] = 0.01   // additional decrement in addition to move cost, for bumping into things
+	ev.Params["LandmarkFOV"] = 360 // field of view for the Landmarks view, in degrees
+
+	// drives: see ConfigDrives for the per-drive params
+	ev.DriveMats = map[string]*DriveMat{
+		"Food":  {Inter: "Energy", Act: "Eat", UsedMat: "FoodWas", RewInter: "FoodRew"},
+		"Water": {Inter: "Hydra", Act: "Drink", UsedMat: "WaterWas", RewInter: "WaterRew"},
+	}
 	ev.Params["EatCost"] = 0.005   // additional decrement in hydration due to eating
 	ev.Params["DrinkCost"] = 0.005 // additional decrement in energy due to drinking
 	ev.Params["EatVal"] = 0.9      // increment in energy due to eating one unit of food
 	ev.Params["DrinkVal"] = 0.9    // increment in hydration due to drinking one unit of water
 	ev.Params["FoodRefresh"] = 100 // time steps before food is refreshed
 	ev.Params["WaterRefresh"] = 50 // time steps before water is refreshed
+	ev.Params["EnergyGenN"] = 50   // number of food items placed by GenWorld
+	ev.Params["HydraGenN"] = 50    // number of water items placed by GenWorld
+	// example of a third drive, Temp, restored by standing (Stay) facing a Warm material --
+	// new drives must be declared here, as SetParams only sets params of declared drives:
+	// ev.Inters = append(ev.Inters, "Temp")
+	// ev.Mats = append(ev.Mats, "Warm")
+	// ev.DriveMats["Warm"] = &DriveMat{Inter: "Temp", Act: "Stay"}
+	// ev.Params["TempDecay"] = 0.002
+	// ev.Params["TempVal"] = 0.1
+	// ev.Params["TempGenN"] = 20
+
+	ev.ConfigOdors()
+	ev.ConfigDaylight()
+	ev.ConfigKinematics()
+	ev.ConfigPlanner()
+
+	ev.Agents = nil
+	ev.AddAgent("Agent0")
+	// ev.AddAgent("Agent1") // uncomment for social foraging with two agents
 
 	ev.Size.Set(100, 100)
 	ev.PatSize.Set(5, 5)
+	ev.PatGen.Defaults()
+	ev.Landmarks = nil
+	// ev.ConfigLandmarks() // uncomment for a cue card and distal landmarks
 	ev.AngInc = 15
 	ev.FOV = 180
 	ev.FoveaSize = 1
 	ev.FoveaAngInc = 5
+	ev.Continuous = false
+	// ev.SetEyes(2, 180, 40) // uncomment for rodent-style two side-facing eyes
 	ev.PopSize = 12
 	ev.PopCode.Defaults()
 	ev.PopCode.SetRange(-0.2, 1.2, 0.1)
+	ev.AngCode.Defaults()
+	ev.AngCode.SetRange(0, 1, 0.1)
 
 	// debugging options:
 	ev.ShowRays = false
 	ev.ShowFovRays = false
 	ev.TraceActGen = false
+	ev.Oracle = false
+
+	// episode termination: default is one endless episode
+	ev.Term.DriveZero = false
+	ev.Term.MaxSteps = 0
+	ev.Term.GoalMat = ""
+	ev.Term.MaxConsume = 0
+	ev.Term.Reset = ResetRespawn
+
+	// landmark changes at each new episode: default is none
+	ev.LandmarkEp.Rotate = 0
+	ev.LandmarkEp.RandRotate = false
+	ev.LandmarkEp.RemoveP = 0
+	// ev.LandmarkEp.Rotate = 90 // uncomment for cue-rotation experiments
+	// ev.LandmarkEp.RandRotate = true
+
+	// sensor noise: default is none
+	ev.Noise.Defaults()
+	// ev.Noise.DepthSD = 0.05 // uncomment for noisy depth
+	// ev.Noise.SetConfusion("Food", "FoodWas", 0.1) // uncomment to sometimes mistake food for eaten food
 
 	ev.Trial.Max = ntrls
+	ev.SeedRand(1)
 
-	ev.ConfigPats()
-	ev.ConfigImpl()
+	ev.DefParams = make(map[string]float32, len(ev.Params))
+	for k, v := range ev.Params {
+		ev.DefParams[k] = v
+	}
+}
 
-	// uncomment to generate a new world
-	ev.GenWorld()
-	ev.SaveWorld("world.tsv")
+// SetParams sets the Params to the defaults set in ConfigDefaults, with
+// given values applied, and configures the drives from them.
+// Returns an error for any name that is not a default or per-drive param
+// (see DriveParamKeys), in which case nothing is changed.
+// Call ConfigImpl and Init afterward for the changes to take effect.
+func (ev *FWorld) SetParams(ps map[string]float32) error {
+	for k := range ps {
+		if _, ok := ev.DefParams[k]; !ok && !ev.IsDriveParam(k) {
+			return fmt.Errorf("FWorld: %v SetParams: param: %s is not a known param", ev.Nm, k)
+		}
+	}
+	ev.Params = make(map[string]float32, len(ev.DefParams)+len(ps))
+	for k, v := range ev.DefParams {
+		ev.Params[k] = v
+	}
+	for k, v := range ps {
+		ev.Params[k] = v
+	}
+	ev.ConfigDrives()
+	return nil
 }
 
-// ConfigPats configures the bit pattern representations of mats and acts
-func (ev *FWorld) ConfigPats() {
+// ConfigPats configures the bit pattern representations of mats and acts:
+// hand crafted ones are loaded from pats.json, and any that are missing
+// are generated according to PatGen, and saved back to pats.json if
+// PatGen.Save -- returns an error if they could not all be generated
+func (ev *FWorld) ConfigPats() error {
 	ev.Pats = make(map[string]*etensor.Float32)
-	for _, m := range ev.Mats {
-		t := &etensor.Float32{}
-		t.SetShape([]int{ev.PatSize.Y, ev.PatSize.X}, nil, []string{"Y", "X"})
-		ev.Pats[m] = t
+	if !ev.PatGen.All {
+		ev.OpenPats("pats.json") // hand crafted..
+	}
+	gen, err := ev.GenPats()
+	if err != nil {
+		return err
 	}
-	for _, a := range ev.Acts {
-		t := &etensor.Float32{}
-		t.SetShape([]int{ev.PatSize.Y, ev.PatSize.X}, nil, []string{"Y", "X"})
-		ev.Pats[a] = t
+	if len(gen) > 0 && ev.PatGen.Save {
+		return ev.SavePats("pats.json")
 	}
-	ev.OpenPats("pats.json") // hand crafted..
+	return nil
 }
 
 // ConfigImpl does the automatic parts of configuration
@@ -257,6 +424,35 @@ func (ev *FWorld) ConfigImpl() {
 	ev.World = &etensor.Int{}
 	ev.World.SetShape([]int{ev.Size.Y, ev.Size.X}, nil, []string{"Y", "X"})
 
+	ev.MatMap = make(map[string]int, len(ev.Mats))
+	for i, m := range ev.Mats {
+		ev.MatMap[m] = i
+	}
+	ev.ActMap = make(map[string]int, len(ev.Acts))
+	for i, m := range ev.Acts {
+		ev.ActMap[m] = i
+	}
+	ev.InterMap = make(map[string]int, len(ev.Inters))
+	for i, m := range ev.Inters {
+		ev.InterMap[m] = i
+	}
+
+	ev.ConfigOdorsImpl()
+	ev.ConfigEntitiesImpl()
+	ev.ConfigLandmarksImpl()
+	ev.ConfigAgentsImpl()
+
+	ev.Run.Scale = env.Run
+	ev.Epoch.Scale = env.Epoch
+	ev.Trial.Scale = env.Trial
+	ev.Tick.Scale = env.Tick
+	ev.Event.Scale = env.Event
+	ev.Scene.Scale = env.Scene
+	ev.Episode.Scale = env.Episode
+}
+
+// ConfigAgentImpl configures the states for the current agent
+func (ev *FWorld) ConfigAgentImpl() {
 	ev.ProxMats = make([]int, 4)
 	ev.ProxPos = make([]evec.Vec2i, 4)
 
@@ -283,9 +479,7 @@ func (ev *FWorld) ConfigImpl() {
 	ps.SetShape([]int{1, 4, 2, 1}, nil, []string{"1", "Pos", "OnOff", "1"})
 	ev.NextStates["ProxSoma"] = ps
 
-	vs := &etensor.Float32{}
-	vs.SetShape([]int{1, ev.PopSize}, nil, []string{"1", "Pop"})
-	ev.NextStates["Vestibular"] = vs
+	ev.ConfigVestibular()
 
 	is := &etensor.Float32{}
 	is.SetShape([]int{1, len(ev.Inters), ev.PopSize, 1}, nil, []string{"1", "Inters", "Pop", "1"})
@@ -295,42 +489,36 @@ func (ev *FWorld) ConfigImpl() {
 	av.SetShape([]int{ev.PatSize.Y, ev.PatSize.X}, nil, []string{"Y", "X"})
 	ev.NextStates["Action"] = av
 
+	ev.ConfigEyesImpl()
+	ev.ConfigOlfactImpl()
+	ev.ConfigDrivesImpl()
+	ev.ConfigClockImpl()
+	ev.ConfigLandmarkViewImpl()
+
 	ev.CopyNextToCur() // get CurStates from NextStates
 
 	ev.FovMats = make([]int, fsz)
 	ev.FovDepths = make([]float32, fsz)
 	ev.FovDepthLogs = make([]float32, fsz)
 
-	ev.MatMap = make(map[string]int, len(ev.Mats))
-	for i, m := range ev.Mats {
-		ev.MatMap[m] = i
-	}
-	ev.ActMap = make(map[string]int, len(ev.Acts))
-	for i, m := range ev.Acts {
-		ev.ActMap[m] = i
-	}
-	ev.InterMap = make(map[string]int, len(ev.Inters))
-	for i, m := range ev.Inters {
-		ev.InterMap[m] = i
-	}
 	ev.InterStates = make(map[string]float32, len(ev.Inters))
 	for _, m := range ev.Inters {
 		ev.InterStates[m] = 0
 	}
-
-	ev.Run.Scale = env.Run
-	ev.Epoch.Scale = env.Epoch
-	ev.Trial.Scale = env.Trial
-	ev.Tick.Scale = env.Tick
-	ev.Event.Scale = env.Event
-	ev.Scene.Scale = env.Scene
-	ev.Episode.Scale = env.Episode
 }
 
 func (ev *FWorld) Validate() error {
 	if ev.Size.IsNil() {
 		return fmt.Errorf("FWorld: %v has size == 0 -- need to Config", ev.Nm)
 	}
+	for _, ey := range ev.Eyes {
+		if ey.FOV <= 0 || ey.FOV%(2*ev.AngInc) != 0 {
+			return fmt.Errorf("FWorld: %v eye: %v FOV: %d must be a positive even multiple of AngInc: %d", ev.Nm, ey.Name, ey.FOV, ev.AngInc)
+		}
+	}
+	if ev.Continuous && ev.Params["MaxSpeed"] <= 0 {
+		return fmt.Errorf("FWorld: %v MaxSpeed: %g must be > 0 in Continuous mode", ev.Nm, ev.Params["MaxSpeed"])
+	}
 	return nil
 }
 
@@ -346,8 +534,13 @@ func (ev *FWorld) String() string {
 // Init is called to restart environment
 func (ev *FWorld) Init(run int) {
 
-	// note: could gen a new random world too..
-	ev.OpenWorld("world.tsv")
+	if ev.GenWorldInit {
+		ev.SeedRand(ev.RndSeed)
+		ev.GenWorld()
+	} else {
+		ev.OpenWorld("world.tsv")
+	}
+	ev.InitLandmarks()
 
 	ev.Run.Init()
 	ev.Epoch.Init()
@@ -362,22 +555,19 @@ func (ev *FWorld) Init(run int) {
 	ev.Tick.Cur = -1
 	ev.Event.Cur = -1
 
-	ev.PosI = ev.Size.DivScalar(2) // start in middle -- could be random..
-	ev.PosF = ev.PosI.ToVec2()
-	for i := 0; i < 4; i++ {
-		ev.ProxMats[i] = 0
-	}
+	ev.SeedRand(ev.RndSeed)
+	ev.Recording = false
+	ev.Ep.Init()
+	ev.UpdateLight()
 
-	ev.Angle = 0
-	ev.RotAng = 0
-	ev.InterStates["Energy"] = 1
-	ev.InterStates["Hydra"] = 1
-	ev.InterStates["BumpPain"] = 0
-	ev.InterStates["FoodRew"] = 0
-	ev.InterStates["WaterRew"] = 0
+	ev.InitAgents()
+	ev.Plans = make(map[string]*PlanCache)
 
 	ev.RefreshEvents = make(map[int]*WEvent)
 	ev.AllEvents = make(map[int]*WEvent)
+
+	ev.InitEntities()
+	ev.InitOdors()
 }
 
 // SetWorld sets given mat at given point coord in world
@@ -390,6 +580,25 @@ func (ev *FWorld) GetWorld(p evec.Vec2i) int {
 	return ev.World.Value([]int{p.Y, p.X})
 }
 
+// InWorld returns true if given point coord is within the world
+func (ev *FWorld) InWorld(p evec.Vec2i) bool {
+	return p.X >= 0 && p.X < ev.Size.X && p.Y >= 0 && p.Y < ev.Size.Y
+}
+
+// ChangeWorld sets given mat at given point coord in world, updating
+// any world state that depends on it, such as odor fields.
+// Use this for changes to the world during a run.
+func (ev *FWorld) ChangeWorld(p evec.Vec2i, mat int) {
+	if ev.IsBarrier(ev.GetWorld(p)) != ev.IsBarrier(mat) { // odor diffuses differently
+		ev.SetWorld(p, mat)
+		ev.InitOdors()
+		return
+	}
+	ev.OdorSource(p, ev.GetWorld(p), -1)
+	ev.SetWorld(p, mat)
+	ev.OdorSource(p, mat, 1)
+}
+
 ////////////////////////////////////////////////////////////////////
 // I/O
 
@@ -519,14 +728,19 @@ func NextVecPoint(cp, v mat32.Vec2) (mat32.Vec2, evec.Vec2i) {
 
 // ScanDepth does simple ray-tracing to find depth and material along each angle vector
 func (ev *FWorld) ScanDepth() {
+	ev.ScanDepthFOV(ev.PosF, ev.Angle, ev.FOV, ev.Depths, ev.DepthLogs, ev.ViewMats)
+}
+
+// ScanDepthFOV does simple ray-tracing to find depth and material along each angle vector
+// within given field of view centered on given heading angle, from given point,
+// recording results in given slices, which must be of size FOV / AngInc + 1
+func (ev *FWorld) ScanDepthFOV(op mat32.Vec2, heading, fov int, depths, depthLogs []float32, viewMats []int) {
 	nmat := len(ev.Mats)
-	_ = nmat
 	idx := 0
-	hang := ev.FOV / 2
+	hang := fov / 2
 	maxld := mat32.Log(1 + mat32.Sqrt(float32(ev.Size.X*ev.Size.X+ev.Size.Y*ev.Size.Y)))
 	for ang := hang; ang >= -hang; ang -= ev.AngInc {
-		v := AngVec(ang + ev.Angle)
-		op := ev.PosF
+		v := AngVec(ang + heading)
 		cp := op
 		gp := evec.Vec2i{}
 		depth := float32(-1)
@@ -539,8 +753,11 @@ func (ev *FWorld) ScanDepth() {
 			if gp.Y < 0 || gp.Y >= ev.Size.Y {
 				break
 			}
-			mat := ev.GetWorld(gp)
-			if mat > 0 && mat <= ev.BarrierIdx {
+			if !ev.InVisRange(cp.DistTo(op)) {
+				break
+			}
+			mat := ev.MatAt(gp)
+			if ev.IsOpaque(mat) {
 				vmat = mat
 				depth = cp.DistTo(op)
 				break
@@ -549,12 +766,12 @@ func (ev *FWorld) ScanDepth() {
 				ev.SetWorld(gp, nmat+idx*2) // visualization
 			}
 		}
-		ev.Depths[idx] = depth
-		ev.ViewMats[idx] = vmat
+		depths[idx] = depth
+		viewMats[idx] = vmat
 		if depth > 0 {
-			ev.DepthLogs[idx] = mat32.Log(1+depth) / maxld
+			depthLogs[idx] = mat32.Log(1+depth) / maxld
 		} else {
-			ev.DepthLogs[idx] = 1
+			depthLogs[idx] = 1
 		}
 		idx++
 	}
@@ -562,13 +779,19 @@ func (ev *FWorld) ScanDepth() {
 
 // ScanFovea does simple ray-tracing to find depth and material for fovea
 func (ev *FWorld) ScanFovea() {
+	ev.ScanFoveaAng(ev.PosF, ev.Angle, ev.FovDepths, ev.FovDepthLogs, ev.FovMats)
+}
+
+// ScanFoveaAng does simple ray-tracing to find depth and material for fovea
+// centered on given heading angle, from given point, recording results
+// in given slices, which must be of size 1 + 2*FoveaSize
+func (ev *FWorld) ScanFoveaAng(op mat32.Vec2, heading int, fovDepths, fovDepthLogs []float32, fovMats []int) {
 	nmat := len(ev.Mats)
 	idx := 0
 	maxld := mat32.Log(1 + mat32.Sqrt(float32(ev.Size.X*ev.Size.X+ev.Size.Y*ev.Size.Y)))
 	for fi := -ev.FoveaSize; fi <= ev.FoveaSize; fi++ {
 		ang := -fi * ev.FoveaAngInc
-		v := AngVec(ang + ev.Angle)
-		op := ev.PosF
+		v := AngVec(ang + heading)
 		cp := op
 		gp := evec.Vec2i{}
 		depth := float32(-1)
@@ -581,7 +804,10 @@ func (ev *FWorld) ScanFovea() {
 			if gp.Y < 0 || gp.Y >= ev.Size.Y {
 				break
 			}
-			mat := ev.GetWorld(gp)
+			if !ev.InVisRange(cp.DistTo(op)) {
+				break
+			}
+			mat := ev.MatAt(gp)
 			if mat > 0 && mat < nmat {
 				vmat = mat
 				depth = cp.DistTo(op)
@@ -591,12 +817,12 @@ func (ev *FWorld) ScanFovea() {
 				ev.SetWorld(gp, nmat+idx*2) // visualization
 			}
 		}
-		ev.FovDepths[idx] = depth
-		ev.FovMats[idx] = vmat
+		fovDepths[idx] = depth
+		fovMats[idx] = vmat
 		if depth > 0 {
-			ev.FovDepthLogs[idx] = mat32.Log(1+depth) / maxld
+			fovDepthLogs[idx] = mat32.Log(1+depth) / maxld
 		} else {
-			ev.FovDepthLogs[idx] = 1
+			fovDepthLogs[idx] = 1
 		}
 		idx++
 	}
@@ -608,29 +834,18 @@ func (ev *FWorld) ScanProx() {
 	for i := 0; i < 4; i++ {
 		v := AngVec(ev.Angle + angs[i])
 		_, gp := NextVecPoint(ev.PosF, v)
-		ev.ProxMats[i] = ev.GetWorld(gp)
+		ev.ProxMats[i] = ev.MatAt(gp)
 		ev.ProxPos[i] = gp
 	}
 }
 
-// IncState increments state by factor, keeping bounded between 0-1
-func (ev *FWorld) IncState(nm string, inc float32) {
-	st := ev.InterStates[nm]
-	st += inc
-	st = mat32.Max(st, 0)
-	st = mat32.Min(st, 1)
-	ev.InterStates[nm] = st
-}
-
 // PassTime does effects of time, initializes rewards
 func (ev *FWorld) PassTime() {
 	ev.Scene.Same()
-	tc := ev.Params["TimeCost"]
-	ev.IncState("Energy", -tc)
-	ev.IncState("Hydra", -tc)
+	ev.Dead = false
+	ev.Consumed = ""
+	ev.DecayDrives()
 	ev.InterStates["BumpPain"] = 0
-	ev.InterStates["FoodRew"] = 0
-	ev.InterStates["WaterRew"] = 0
 }
 
 ////////////////////////////////////////////////////////////////////
@@ -669,38 +884,41 @@ func (ev *FWorld) NewEvent(act, mat int, matpos evec.Vec2i) *WEvent {
 // AddNewEventRefresh adds event to RefreshEvents (a consumable was consumed).
 // always adds to AllEvents
 func (ev *FWorld) AddNewEventRefresh(wev *WEvent) {
-	ev.RefreshEvents[wev.Tick] = wev
-	ev.AllEvents[wev.Tick] = wev
+	idx := len(ev.AllEvents)
+	ev.RefreshEvents[idx] = wev
+	ev.AllEvents[idx] = wev
 }
 
-// RefreshWorld refreshes consumables
+// RefreshWorld refreshes consumables, after the refresh time of their drive,
+// which can depend on the time of day (see RefreshTime)
 func (ev *FWorld) RefreshWorld() {
 	ct := ev.Tick.Cur
-	fr := int(ev.Params["FoodRefresh"])
-	wr := int(ev.Params["WaterRefresh"])
-	fmat := ev.MatMap["Food"]
-	wmat := ev.MatMap["Water"]
-	for t, wev := range ev.RefreshEvents {
-		setmat := 0
-		switch wev.Mat {
-		case fmat:
-			if t+fr < ct {
-				setmat = fmat
-			}
-		case wmat:
-			if t+wr < ct {
-				setmat = wmat
-			}
+	for idx, wev := range ev.RefreshEvents {
+		dr := ev.DriveForMat(ev.MatName(wev.Mat))
+		if dr == nil {
+			delete(ev.RefreshEvents, idx)
+			continue
 		}
-		if setmat != 0 {
-			ev.SetWorld(wev.MatPos, setmat)
-			delete(ev.RefreshEvents, t)
+		if wev.Tick+ev.RefreshTime(dr) < ct {
+			ev.ChangeWorld(wev.MatPos, wev.Mat)
+			delete(ev.RefreshEvents, idx)
 		}
 	}
 }
 
-// TakeAct takes the action, updates state
+// TakeAct takes the action for the current agent, updates state
 func (ev *FWorld) TakeAct(act int) {
+	ev.DoAct(act)
+	ev.UpdateEntities()
+	ev.ScanState()
+	ev.CheckEpisode()
+}
+
+// DoAct does the effects of the action for the current agent, without
+// updating the rest of the world or rendering the state.  The proximal
+// materials are rescanned first, as they may have been changed by other
+// agents or world refresh since the last render.
+func (ev *FWorld) DoAct(act int) {
 	as := ""
 	if act >= len(ev.Acts) || act < 0 {
 		as = "Stay"
@@ -708,105 +926,112 @@ func (ev *FWorld) TakeAct(act int) {
 		as = ev.Acts[act]
 	}
 	ev.PassTime()
+	ev.ScanProx()
+
+	var mvf, rotf float32
+	var bump bool
+	if ev.Continuous {
+		mvf, rotf, bump = ev.MoveCont(as)
+	} else {
+		mvf, rotf, bump = ev.MoveGrid(as)
+	}
+	ev.DriveCosts(as, mvf, rotf, bump)
+	ev.Consume(act, as)
+	ev.CheckDeath()
+}
 
+// MoveGrid does the movement for given action in the discrete grid mode
+// for the current agent, returning the amount of movement (1 = one step),
+// rotation (1 = AngInc), and whether it bumped into a barrier.
+func (ev *FWorld) MoveGrid(as string) (mvf, rotf float32, bump bool) {
 	ev.RotAng = 0
 
 	nmat := len(ev.Mats)
 	frmat := ints.MinInt(ev.ProxMats[0], nmat)
 	behmat := ev.ProxMats[3] // behind
-	front := ev.Mats[frmat]  // state in front
-
-	mvc := ev.Params["MoveCost"]
-	rotc := ev.Params["RotCost"]
-	bumpc := ev.Params["BumpCost"]
-
-	ecost := float32(0) // extra energy cost
-	hcost := float32(0) // extra hydra cost
 
 	switch as {
-	case "Stay":
 	case "Left":
 		ev.RotAng = ev.AngInc
 		ev.Angle = AngMod(ev.Angle + ev.RotAng)
-		ecost = rotc
-		hcost = rotc
+		rotf = 1
 	case "Right":
 		ev.RotAng = -ev.AngInc
 		ev.Angle = AngMod(ev.Angle + ev.RotAng)
-		ecost = rotc
-		hcost = rotc
+		rotf = 1
 	case "Forward":
-		ecost = mvc
-		hcost = mvc
-		if frmat > 0 && frmat <= ev.BarrierIdx {
+		mvf = 1
+		if ev.IsBarrier(frmat) {
 			ev.InterStates["BumpPain"] = 1
-			ecost += bumpc
-			hcost += bumpc
+			bump = true
 		} else {
 			ev.PosF, ev.PosI = NextVecPoint(ev.PosF, AngVec(ev.Angle))
 		}
 	case "Backward":
-		ecost = mvc
-		hcost = mvc
-		if behmat > 0 && behmat <= ev.BarrierIdx {
+		mvf = 1
+		if ev.IsBarrier(behmat) {
 			ev.InterStates["BumpPain"] = 1
-			ecost += bumpc
-			hcost += bumpc
+			bump = true
 		} else {
 			ev.PosF, ev.PosI = NextVecPoint(ev.PosF, AngVec(AngMod(ev.Angle+180)))
 		}
-	case "Eat":
-		if front == "Food" {
-			ev.InterStates["FoodRew"] = 1
-			hcost += ev.Params["EatCost"]
-			ecost -= ev.Params["EatVal"]
-			ev.AddNewEventRefresh(ev.NewEvent(act, frmat, ev.ProxPos[0]))
-			ev.SetWorld(ev.ProxPos[0], ev.MatMap["FoodWas"])
-			ev.Event.Set(0)
-			ev.Scene.Incr()
-		}
-	case "Drink":
-		if front == "Water" {
-			ev.InterStates["WaterRew"] = 1
-			ecost += ev.Params["DrinkCost"]
-			hcost -= ev.Params["DrinkVal"]
-			ev.AddNewEventRefresh(ev.NewEvent(act, frmat, ev.ProxPos[0]))
-			ev.SetWorld(ev.ProxPos[0], ev.MatMap["WaterWas"])
-			ev.Event.Set(0)
-			ev.Scene.Incr()
-		}
 	}
+	return
+}
+
+// ScanState scans the world from the current agent's point of view,
+// and renders the resulting state into NextStates
+func (ev *FWorld) ScanState() {
 	ev.ScanDepth()
 	ev.ScanFovea()
+	ev.ScanEyes()
 	ev.ScanProx()
-
-	ev.IncState("Energy", -ecost)
-	ev.IncState("Hydra", -hcost)
+	ev.ScanOdor()
 
 	ev.RenderState()
 }
 
 // RenderView renders the current view state to NextStates tensor input states
 func (ev *FWorld) RenderView() {
-	dv := ev.NextStates["Depth"]
-	for i := 0; i < ev.NFOVRays; i++ {
+	ev.RenderDepth(ev.NextStates["Depth"], ev.DepthLogs)
+	ev.RenderFovea(ev.NextStates["FovDepth"], ev.NextStates["Fovea"], ev.FovDepths, ev.FovDepthLogs, ev.FovMats)
+	ev.RenderEyes()
+}
+
+// RenderDepth renders given depth logs into given Depth state,
+// with any sensor Noise
+func (ev *FWorld) RenderDepth(dv *etensor.Float32, depthLogs []float32) {
+	for i, dl := range depthLogs {
 		sv := dv.SubSpace([]int{0, i}).(*etensor.Float32)
-		ev.PopCode.Encode(&sv.Values, ev.DepthLogs[i], ev.PopSize, false)
+		if ev.NoiseDrop() {
+			sv.SetZeros()
+			continue
+		}
+		ev.PopCode.Encode(&sv.Values, ev.NoiseDepth(dl), ev.PopSize, false)
 	}
+}
 
+// RenderFovea renders given fovea depths, depth logs and materials into given
+// FovDepth and Fovea states, with any sensor Noise
+func (ev *FWorld) RenderFovea(fd, fv *etensor.Float32, fovDepths, fovDepthLogs []float32, fovMats []int) {
 	fsz := 1 + 2*ev.FoveaSize
-	fd := ev.NextStates["FovDepth"]
-	fv := ev.NextStates["Fovea"]
 	for i := 0; i < fsz; i++ {
 		sv := fd.SubSpace([]int{0, i}).(*etensor.Float32)
-		ev.PopCode.Encode(&sv.Values, ev.FovDepthLogs[i], ev.PopSize, false)
-		fm := ev.FovMats[i]
+		if ev.NoiseDrop() {
+			sv.SetZeros()
+			fv.SubSpace([]int{0, i}).(*etensor.Float32).SetZeros()
+			continue
+		}
+		ev.PopCode.Encode(&sv.Values, ev.NoiseDepth(fovDepthLogs[i]), ev.PopSize, false)
+		fm := fovMats[i]
 		if fm < len(ev.Mats) {
+			fm = ev.NoiseMat(fm, fovDepths[i])
 			sv := fv.SubSpace([]int{0, i}).(*etensor.Float32)
 			ms := ev.Mats[fm]
 			mp, ok := ev.Pats[ms]
 			if ok {
 				sv.CopyFrom(mp)
+				ev.ContrastPat(sv.Values)
 			}
 		}
 	}
@@ -835,10 +1060,15 @@ func (ev *FWorld) RenderInters() {
 	}
 }
 
-// RenderVestib renders vestibular state
+// RenderVestib renders vestibular state, with any drift from sensor Noise
 func (ev *FWorld) RenderVestibular() {
+	ev.UpdateVestDrift()
+	if ev.Continuous {
+		ev.RenderVestibularCont()
+		return
+	}
 	vs := ev.NextStates["Vestibular"]
-	nv := 0.5*(float32(-ev.RotAng)/15) + 0.5
+	nv := 0.5*(float32(-ev.RotAng)/15) + 0.5 + ev.VestDrift
 	ev.PopCode.Encode(&vs.Values, nv, ev.PopSize, false)
 }
 
@@ -860,10 +1090,14 @@ func (ev *FWorld) RenderState() {
 	ev.RenderProxSoma()
 	ev.RenderInters()
 	ev.RenderVestibular()
+	ev.RenderOlfact()
+	ev.RenderUrgency()
+	ev.RenderClock()
+	ev.RenderLandmarks()
 	ev.RenderAction()
 }
 
-// CopyNextToCur copy next state to current state
+// CopyNextToCur copy next state to current state, for current agent
 func (ev *FWorld) CopyNextToCur() {
 	for k, ns := range ev.NextStates {
 		cs, ok := ev.CurStates[k]
@@ -876,25 +1110,43 @@ func (ev *FWorld) CopyNextToCur() {
 	}
 }
 
-// Step is called to advance the environment state
+// Step is called to advance the environment state.  If the episode
+// terminated on the last action, a new episode is started (see NewEpisode),
+// and Step returns false to signal the episode boundary.
 func (ev *FWorld) Step() bool {
 	ev.Epoch.Same() // good idea to just reset all non-inner-most counters at start
-	ev.CopyNextToCur()
+	ev.Episode.Same()
+	newep := false
+	if ev.Ep.Done() {
+		ev.NewEpisode()
+		newep = true
+	}
+	ev.AgentsDo(func(ag *Agent) {
+		ev.CopyNextToCur()
+	})
 	ev.Tick.Incr()
 	ev.Event.Incr()
+	ev.UpdateLight()
 	ev.RefreshWorld()
 	if ev.Trial.Incr() { // true if wraps around Max back to 0
 		ev.Epoch.Incr()
 	}
-	return true
+	return !newep
 }
 
-func (ev *FWorld) Action(action string, nop etensor.Tensor) {
+// Action takes given action for the current agent -- for the Move action in
+// Continuous mode, vals has the normalized linear and angular velocity (-1..1)
+func (ev *FWorld) Action(action string, vals etensor.Tensor) {
 	a, ok := ev.ActMap[action]
 	if !ok {
 		fmt.Printf("Action not recognized: %s\n", action)
 		return
 	}
+	if action == "Move" && vals != nil && vals.Len() >= 2 {
+		ev.MoveLin = float32(vals.FloatVal1D(0))
+		ev.MoveAng = float32(vals.FloatVal1D(1))
+	}
+	ev.RecordAct(action, 0)
 	ev.Act = a
 	ev.TakeAct(ev.Act)
 }
@@ -1021,8 +1273,8 @@ func (ev *FWorld) WorldLine(st, ed evec.Vec2i, mat int) {
 func (ev *FWorld) WorldRandom(n, mat int) {
 	cnt := 0
 	for cnt < n {
-		px := rand.Intn(ev.Size.X)
-		py := rand.Intn(ev.Size.Y)
+		px := ev.Rand.Intn(ev.Size.X, -1)
+		py := ev.Rand.Intn(ev.Size.Y, -1)
 		ix := []int{py, px}
 		cm := ev.World.Value(ix)
 		if cm == 0 {
@@ -1043,8 +1295,6 @@ func (ev *FWorld) WorldRect(st, ed evec.Vec2i, mat int) {
 // GenWorld generates a world -- edit to create in way desired
 func (ev *FWorld) GenWorld() {
 	wall := ev.MatMap["Wall"]
-	food := ev.MatMap["Food"]
-	water := ev.MatMap["Water"]
 	ev.World.SetZeros()
 	// always start with a wall around the entire world -- no seeing the turtles..
 	ev.WorldRect(evec.Vec2i{0, 0}, evec.Vec2i{ev.Size.X - 1, ev.Size.Y - 1}, wall)
@@ -1058,8 +1308,11 @@ func (ev *FWorld) GenWorld() {
 	ctr := ev.Size.DivScalar(2)
 	ev.SetWorld(ctr, wall)
 
-	ev.WorldRandom(50, food)
-	ev.WorldRandom(50, water)
+	for _, dr := range ev.Drives {
+		if mat, ok := ev.MatMap[dr.Mat]; ok && dr.GenN > 0 {
+			ev.WorldRandom(dr.GenN, mat)
+		}
+	}
 
 	// clear center
 	ev.SetWorld(ctr, 0)
@@ -1076,10 +1329,18 @@ func (ev *FWorld) ActGenTrace(desc string, act int) {
 	fmt.Printf("%s: act: %s\n", desc, ev.Acts[act])
 }
 
-// ActGen generates an action for current situation based on simple
-// coded heuristics -- i.e., what subcortical evolutionary instincts provide.
+// ActGen generates an action for current situation: if Oracle, then
+// the optimal action from OracleAct, otherwise using ActGenHeur.
 func (ev *FWorld) ActGen() int {
-	wall := ev.MatMap["Wall"]
+	if ev.Oracle {
+		return ev.OracleAct()
+	}
+	return ev.ActGenHeur()
+}
+
+// ActGenHeur generates an action for current situation based on simple
+// coded heuristics -- i.e., what subcortical evolutionary instincts provide.
+func (ev *FWorld) ActGenHeur() int {
 	food := ev.MatMap["Food"]
 	water := ev.MatMap["Water"]
 	left := ev.ActMap["Left"]
@@ -1106,14 +1367,14 @@ func (ev *FWorld) ActGen() int {
 		case mat == food:
 			fwt += 1 - ev.FovDepthLogs[i] // more weight if closer
 			fdp = mat32.Min(fdp, ev.FovDepths[i])
-		case mat <= ev.BarrierIdx:
+		case mat == 0 || ev.IsBarrier(mat):
 		default:
 			fovnonwall = mat
 		}
 		fovdp = mat32.Min(fovdp, ev.FovDepths[i])
 	}
-	fwt *= 1 - ev.InterStates["Energy"] // weight by need
-	wwt *= 1 - ev.InterStates["Hydra"]
+	fwt *= ev.DriveUrgency("Energy") // weight by need
+	wwt *= ev.DriveUrgency("Hydra")
 
 	fovmat := ev.FovMats[ev.FoveaSize]
 	fovmats := ev.Mats[fovmat]
@@ -1147,6 +1408,8 @@ func (ev *FWorld) ActGen() int {
 		lpow := math.Exp(ldf * smaxpow)
 		rlp = float64(lpow / (rpow + lpow))
 	}
+	// note: ActGen uses the global rand, not ev.Rand, because it is not called
+	// during Replay, so drawing from ev.Rand would make the replay diverge
 	rlact := left
 	if erand.BoolP(rlp, -1) {
 		rlact = right
@@ -1159,7 +1422,7 @@ func (ev *FWorld) ActGen() int {
 
 	act := ev.ActMap["Forward"] // default
 	switch {
-	case frmat == wall:
+	case ev.IsBarrier(frmat): // walls, cue cards and other agents
 		if lastact == left || lastact == right {
 			act = lastact // keep going
 			ev.ActGenTrace("at wall, keep turning", act)
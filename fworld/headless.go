@@ -0,0 +1,273 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/emer/etable/etable"
+	"github.com/goki/gi/gi"
+)
+
+// Runner runs the FWorld without the GUI, for a given number of steps
+// of a given policy, accumulating TrajStats, e.g., for tuning world
+// parameters such as FoodRefresh and MoveCost in batch.
+type Runner struct {
+
+	// number of steps to run for each parameter combination
+	Steps int `desc:"number of steps to run for each parameter combination"`
+
+	// action policy: actgen = ActGen heuristics, oracle = ActGen with Oracle, random = uniformly random actions, script = actions from Script, in a loop
+	Policy string `desc:"action policy: actgen = ActGen heuristics, oracle = ActGen with Oracle, random = uniformly random actions, script = actions from Script, in a loop"`
+
+	// sequence of action names for the script policy
+	Script []string `desc:"sequence of action names for the script policy"`
+
+	// random seed for the actgen and random policies
+	Seed int64 `desc:"random seed for the actgen and random policies"`
+
+	// prefix for all output files
+	Prefix string `desc:"prefix for all output files"`
+
+	// save occupancy heatmap images
+	PNG bool `desc:"save occupancy heatmap images"`
+
+	// save the per-tick trajectory of the first agent
+	Traj bool `desc:"save the per-tick trajectory of the first agent"`
+
+	// parameter values applied to all runs
+	Params map[string]float32 `desc:"parameter values applied to all runs"`
+
+	// values of parameters to search over -- every combination is run
+	Sweep map[string][]float32 `desc:"values of parameters to search over -- every combination is run"`
+}
+
+// ParseParams parses a list of parameter values of the form Name=val,Name2=val2
+func ParseParams(s string) (map[string]float32, error) {
+	ps := make(map[string]float32)
+	for _, pv := range strings.Split(s, ",") {
+		pv = strings.TrimSpace(pv)
+		if pv == "" {
+			continue
+		}
+		kv := strings.SplitN(pv, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("param not of form Name=val: %s", pv)
+		}
+		v, err := strconv.ParseFloat(kv[1], 32)
+		if err != nil {
+			return nil, err
+		}
+		ps[kv[0]] = float32(v)
+	}
+	return ps, nil
+}
+
+// ParseSweep parses a list of parameter value lists of the form
+// Name=v1,v2,v3;Name2=v1,v2
+func ParseSweep(s string) (map[string][]float32, error) {
+	sw := make(map[string][]float32)
+	for _, pv := range strings.Split(s, ";") {
+		pv = strings.TrimSpace(pv)
+		if pv == "" {
+			continue
+		}
+		kv := strings.SplitN(pv, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("sweep not of form Name=v1,v2: %s", pv)
+		}
+		for _, vs := range strings.Split(kv[1], ",") {
+			v, err := strconv.ParseFloat(strings.TrimSpace(vs), 32)
+			if err != nil {
+				return nil, err
+			}
+			sw[kv[0]] = append(sw[kv[0]], float32(v))
+		}
+	}
+	return sw, nil
+}
+
+// SweepNames returns the names of the Sweep params in sorted order
+func (rn *Runner) SweepNames() []string {
+	nms := make([]string, 0, len(rn.Sweep))
+	for k := range rn.Sweep {
+		nms = append(nms, k)
+	}
+	sort.Strings(nms)
+	return nms
+}
+
+// SweepSets returns all the combinations of the Sweep param values,
+// each including the Params -- a single set of Params if no Sweep
+func (rn *Runner) SweepSets() []map[string]float32 {
+	sets := []map[string]float32{{}}
+	for k, v := range rn.Params {
+		sets[0][k] = v
+	}
+	for _, pn := range rn.SweepNames() {
+		var nsets []map[string]float32
+		for _, ps := range sets {
+			for _, v := range rn.Sweep[pn] {
+				np := make(map[string]float32, len(ps)+1)
+				for k, pv := range ps {
+					np[k] = pv
+				}
+				np[pn] = v
+				nsets = append(nsets, np)
+			}
+		}
+		sets = nsets
+	}
+	return sets
+}
+
+// SetPrefix returns the output file prefix for given param set
+func (rn *Runner) SetPrefix(ps map[string]float32) string {
+	pfx := rn.Prefix
+	for _, pn := range rn.SweepNames() {
+		pfx += fmt.Sprintf("_%s_%g", pn, ps[pn])
+	}
+	return pfx
+}
+
+// PolicyAct returns the action for the current agent according to the Policy,
+// at given step
+func (rn *Runner) PolicyAct(ev *FWorld, step int) string {
+	switch rn.Policy {
+	case "random":
+		return ev.Acts[rand.Intn(len(ev.Acts))]
+	case "script":
+		if len(rn.Script) == 0 {
+			return "Stay"
+		}
+		return rn.Script[step%len(rn.Script)]
+	default:
+		return ev.Acts[ev.ActGen()]
+	}
+}
+
+// Config configures the world once for all the runs, without saving the
+// patterns, and with the world generated in Init instead of loaded from
+// world.tsv, so that no files other than the outputs are written --
+// returns an error if the patterns could not be generated
+func (rn *Runner) Config(ev *FWorld) error {
+	ev.ConfigDefaults(rn.Steps)
+	ev.PatGen.Save = false
+	ev.GenWorldInit = true
+	return ev.ConfigPats()
+}
+
+// Run runs the world for Steps with given param set, returning the stats,
+// or an error if the param set has unknown params -- Config must be called first
+func (rn *Runner) Run(ev *FWorld, ps map[string]float32) (*TrajStats, error) {
+	if err := ev.SetParams(ps); err != nil {
+		return nil, err
+	}
+	ev.ConfigImpl()
+	ev.Oracle = rn.Policy == "oracle"
+	ev.RndSeed = 1 // same world for each run
+	ev.Init(0)
+	rand.Seed(rn.Seed)
+	ts := &TrajStats{}
+	ts.Init(ev)
+	for i := 0; i < rn.Steps; i++ {
+		if len(ev.Agents) > 1 {
+			acts := make(map[string]string, len(ev.Agents))
+			ev.AgentsDo(func(ag *Agent) {
+				acts[ag.ID] = rn.PolicyAct(ev, i)
+			})
+			ev.ActionAgents(acts)
+		} else {
+			ev.Action(rn.PolicyAct(ev, i), nil)
+		}
+		ts.Record(ev)
+		ev.Step()
+	}
+	return ts, nil
+}
+
+// RunAll runs all the SweepSets, saving the stats for each, and the
+// summary stats for all of them in _summary.tsv
+func (rn *Runner) RunAll(ev *FWorld) error {
+	sets := rn.SweepSets()
+	pnms := rn.SweepNames()
+	for pn := range rn.Params {
+		if _, has := rn.Sweep[pn]; !has {
+			pnms = append(pnms, pn)
+		}
+	}
+	if err := rn.Config(ev); err != nil {
+		return err
+	}
+	sum := etable.NewTable("FWorldSummary")
+	for i, ps := range sets {
+		pfx := rn.SetPrefix(ps)
+		fmt.Printf("run: %d of %d: %s\n", i+1, len(sets), pfx)
+		ts, err := rn.Run(ev, ps)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			ts.ConfigSummaryTable(ev, sum, pnms)
+		}
+		ts.SummaryRow(ev, sum, i, pnms)
+		if err := ts.Save(ev, pfx, rn.PNG, rn.Traj); err != nil {
+			return err
+		}
+	}
+	return sum.SaveCSV(gi.FileName(rn.Prefix+"_summary.tsv"), etable.Tab, etable.Headers)
+}
+
+// CmdArgs parses the command-line args, and runs the world without the
+// GUI as configured by them if -nogui is set, returning true if so
+func (ss *Sim) CmdArgs() bool {
+	rn := &Runner{}
+	var nogui bool
+	var params, sweep, script string
+	flag.BoolVar(&nogui, "nogui", false, "run without the GUI, for -steps of the -policy for each parameter combination, saving stats to files")
+	flag.IntVar(&rn.Steps, "steps", 10000, "number of steps to run for each parameter combination")
+	flag.StringVar(&rn.Policy, "policy", "actgen", "action policy: actgen, oracle, random, or script")
+	flag.StringVar(&script, "script", "", "file with whitespace-separated action names for the script policy, run in a loop")
+	flag.Int64Var(&rn.Seed, "seed", 1, "random seed for the actgen and random policies")
+	flag.StringVar(&rn.Prefix, "out", "fworld", "prefix for all output files")
+	flag.BoolVar(&rn.PNG, "png", true, "save occupancy heatmap images")
+	flag.BoolVar(&rn.Traj, "traj", true, "save the per-tick trajectory, with interoceptive states, of the first agent")
+	flag.StringVar(&params, "params", "", "parameter values for all runs, e.g.: MoveCost=0.002,TimeCost=0.001")
+	flag.StringVar(&sweep, "sweep", "", "parameter values to search over in all combinations, e.g.: FoodRefresh=50,100,200;MoveCost=0.001,0.002")
+	flag.Parse()
+	if !nogui {
+		return false
+	}
+
+	var err error
+	if rn.Params, err = ParseParams(params); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if rn.Sweep, err = ParseSweep(sweep); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if script != "" {
+		b, err := ioutil.ReadFile(script)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		rn.Script = strings.Fields(string(b))
+	}
+	if err := rn.RunAll(&ss.World); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return true
+}
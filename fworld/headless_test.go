@@ -0,0 +1,77 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/emer/etable/etable"
+	"github.com/goki/gi/gi"
+)
+
+func TestRunnerSweep(t *testing.T) {
+	dir := testDir(t)
+	pats, _ := ioutil.ReadFile("pats.json")
+	sw, err := ParseSweep("FoodRefresh=10,1000;MoveCost=0.001,0.002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rn := &Runner{Steps: 200, Policy: "actgen", Seed: 1, Prefix: "tune", Sweep: sw}
+	ev := &FWorld{}
+	if err := rn.RunAll(ev); err != nil {
+		t.Fatal(err)
+	}
+
+	sum := etable.NewTable("sum")
+	if err := sum.OpenCSV(gi.FileName("tune_summary.tsv"), etable.Tab); err != nil {
+		t.Fatal(err)
+	}
+	if sum.Rows != 4 {
+		t.Fatalf("summary rows: %d != 4 param combinations", sum.Rows)
+	}
+	for i, ps := range rn.SweepSets() {
+		for pn, v := range ps {
+			if sv := sum.CellFloat(pn, i); float32(sv) != v {
+				t.Errorf("summary row: %d param: %s: %g != %g", i, pn, sv, v)
+			}
+		}
+		if _, err := os.Stat(rn.SetPrefix(ps) + "_occupancy.tsv"); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "world.tsv")); err == nil {
+		t.Error("world.tsv written by the runner")
+	}
+	if npats, _ := ioutil.ReadFile("pats.json"); string(npats) != string(pats) {
+		t.Error("pats.json changed by the runner")
+	}
+}
+
+func TestRunnerParams(t *testing.T) {
+	testDir(t)
+	rn := &Runner{Steps: 10, Policy: "actgen", Seed: 1}
+	ev := &FWorld{}
+	if err := rn.Config(ev); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rn.Run(ev, map[string]float32{"FoodRefresh": 10, "EatVal": 0.5}); err != nil {
+		t.Fatal(err)
+	}
+	if dr := ev.Drives[0]; dr.Inter != "Energy" || dr.Refresh != 10 || dr.Val != 0.5 {
+		t.Errorf("FoodRefresh and EatVal not applied to the Energy drive: %+v", dr)
+	}
+	if _, err := rn.Run(ev, map[string]float32{"FoodRefresh": 10}); err != nil {
+		t.Fatal(err)
+	}
+	if dr := ev.Drives[0]; dr.Refresh != 10 || dr.Val != 0.9 {
+		t.Errorf("params from the previous run not reset to defaults: %+v", dr)
+	}
+	if _, err := rn.Run(ev, map[string]float32{"FoodRefrsh": 10}); err == nil {
+		t.Error("no error for unknown param")
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/emer/emergent/evec"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/mat32"
+)
+
+// ConfigKinematics configures the default parameters for the
+// Continuous locomotion mode
+func (ev *FWorld) ConfigKinematics() {
+	ev.Params["MaxSpeed"] = 0.5   // maximum linear speed in Continuous mode, in cells per tick
+	ev.Params["MaxAngVel"] = 15   // maximum angular velocity for Move action in Continuous mode, in degrees per tick
+	ev.Params["BodyRadius"] = 0.3 // radius of the agent's body for collisions in Continuous mode, in cells
+}
+
+// ConfigVestibular configures the Vestibular state for the current agent:
+// in Continuous mode it has separate rows for angular and linear acceleration
+func (ev *FWorld) ConfigVestibular() {
+	vs := &etensor.Float32{}
+	if ev.Continuous {
+		vs.SetShape([]int{2, ev.PopSize}, nil, []string{"AngLin", "Pop"})
+	} else {
+		vs.SetShape([]int{1, ev.PopSize}, nil, []string{"1", "Pop"})
+	}
+	ev.NextStates["Vestibular"] = vs
+}
+
+// MoveCont does the movement for given action in Continuous mode for the
+// current agent, returning the amount of movement (1 = one step at MaxSpeed),
+// rotation (1 = AngInc), and whether it bumped into a barrier.
+// Each action sets the linear and angular velocity for this tick:
+// Forward / Backward move at MaxSpeed, Left / Right rotate by AngInc,
+// Move uses the MoveLin and MoveAng values, and all other actions stop.
+// Accelerations are the change in velocity from the previous tick.
+func (ev *FWorld) MoveCont(as string) (mvf, rotf float32, bump bool) {
+	maxsp := ev.Params["MaxSpeed"]
+	maxang := ev.Params["MaxAngVel"]
+	spd := float32(0)
+	angv := float32(0)
+	switch as {
+	case "Left":
+		angv = float32(ev.AngInc)
+	case "Right":
+		angv = -float32(ev.AngInc)
+	case "Forward":
+		spd = maxsp
+	case "Backward":
+		spd = -maxsp
+	case "Move":
+		spd = maxsp * mat32.Clamp(ev.MoveLin, -1, 1)
+		angv = maxang * mat32.Clamp(ev.MoveAng, -1, 1)
+	}
+	ev.LinAcc = spd - ev.Speed
+	ev.AngAcc = angv - ev.AngVel
+	ev.Speed = spd
+	ev.AngVel = angv
+
+	ev.AngleF += angv
+	if ev.AngleF < 0 {
+		ev.AngleF += 360
+	} else if ev.AngleF >= 360 {
+		ev.AngleF -= 360
+	}
+	ev.Angle = AngMod(int(mat32.Round(ev.AngleF)))
+	ev.RotAng = int(mat32.Round(angv))
+
+	if maxsp > 0 { // otherwise spd is 0 and there is no movement
+		mvf = mat32.Abs(spd) / maxsp
+	}
+	rotf = mat32.Abs(angv) / float32(ev.AngInc)
+	if spd != 0 && ev.MoveCircle(spd) {
+		ev.InterStates["BumpPain"] = 1
+		bump = true
+	}
+	return
+}
+
+// MoveCircle moves the agent's circular body by given distance along
+// the current AngleF heading (negative = backward), in sub-steps no larger
+// than the BodyRadius, sliding along any barriers that are hit.
+// Returns true if a barrier was hit.
+func (ev *FWorld) MoveCircle(dist float32) bool {
+	rad := ev.Params["BodyRadius"]
+	a := mat32.DegToRad(ev.AngleF)
+	dv := mat32.Vec2{mat32.Cos(a), mat32.Sin(a)}.MulScalar(dist)
+	nsub := int(mat32.Ceil(mat32.Abs(dist) / rad))
+	if nsub < 1 {
+		nsub = 1
+	}
+	dv = dv.DivScalar(float32(nsub))
+	bumped := false
+	for i := 0; i < nsub; i++ {
+		np := ev.PosF.Add(dv)
+		if !ev.Collides(np) {
+			ev.PosF = np
+			continue
+		}
+		bumped = true
+		xp := mat32.Vec2{np.X, ev.PosF.Y}
+		yp := mat32.Vec2{ev.PosF.X, np.Y}
+		switch {
+		case dv.X != 0 && !ev.Collides(xp):
+			ev.PosF = xp
+		case dv.Y != 0 && !ev.Collides(yp):
+			ev.PosF = yp
+		}
+	}
+	ev.PosI = evec.NewVec2iFmVec2Round(ev.PosF)
+	return bumped
+}
+
+// Collides returns true if the agent's circular body of BodyRadius at
+// given point overlaps any barrier cell, other agent, or the edge of the world.
+// Each cell is a unit square centered on its integer coordinates.
+func (ev *FWorld) Collides(p mat32.Vec2) bool {
+	rad := ev.Params["BodyRadius"]
+	sx := int(mat32.Floor(p.X - rad + 0.5))
+	ex := int(mat32.Floor(p.X + rad + 0.5))
+	sy := int(mat32.Floor(p.Y - rad + 0.5))
+	ey := int(mat32.Floor(p.Y + rad + 0.5))
+	for y := sy; y <= ey; y++ {
+		for x := sx; x <= ex; x++ {
+			c := evec.Vec2i{x, y}
+			if ev.InWorld(c) && !ev.IsBarrier(ev.MatAt(c)) {
+				continue
+			}
+			cx := mat32.Clamp(p.X, float32(x)-0.5, float32(x)+0.5)
+			cy := mat32.Clamp(p.Y, float32(y)-0.5, float32(y)+0.5)
+			dx := p.X - cx
+			dy := p.Y - cy
+			if dx*dx+dy*dy < rad*rad {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RenderVestibularCont renders the vestibular state in Continuous mode:
+// angular acceleration (where < .5 = accelerating to the left, as for
+// rotation in the discrete mode) and linear acceleration (> .5 = forward)
+func (ev *FWorld) RenderVestibularCont() {
+	vs := ev.NextStates["Vestibular"]
+	maxsp := ev.Params["MaxSpeed"]
+	maxang := ev.Params["MaxAngVel"]
+	sv := vs.SubSpace([]int{0}).(*etensor.Float32)
+	nv := 0.5 + ev.VestDrift
+	if maxang > 0 {
+		nv += 0.5 * (-ev.AngAcc / (2 * maxang))
+	}
+	ev.PopCode.Encode(&sv.Values, nv, ev.PopSize, false)
+	sv = vs.SubSpace([]int{1}).(*etensor.Float32)
+	nv = 0.5 + ev.VestDrift
+	if maxsp > 0 {
+		nv += 0.5 * (ev.LinAcc / (2 * maxsp))
+	}
+	ev.PopCode.Encode(&sv.Values, nv, ev.PopSize, false)
+}
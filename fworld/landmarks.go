@@ -0,0 +1,269 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/emer/emergent/erand"
+	"github.com/emer/emergent/evec"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/mat32"
+)
+
+// Landmark is a distal visual cue for allocentric navigation, seen in the
+// Landmarks view from anywhere in the world, in terms of its bearing relative
+// to the agent's heading and its distance.  A landmark can be a cue card
+// painted onto the wall cells around its position, using its material,
+// so that it is also seen in the depth and fovea views, or it can be beyond
+// the walls of the world, only seen in the Landmarks view.
+type Landmark struct {
+
+	// name of landmark
+	Name string `desc:"name of landmark"`
+
+	// material painted onto the wall cells for a cue card -- must be in Mats -- empty = not painted, only seen in the Landmarks view
+	Mat string `desc:"material painted onto the wall cells for a cue card -- must be in Mats -- empty = not painted, only seen in the Landmarks view"`
+
+	// home position, in world coordinates, which can be beyond the world for distal landmarks -- cue cards should be on the outer wall
+	Home mat32.Vec2 `desc:"home position, in world coordinates, which can be beyond the world for distal landmarks -- cue cards should be on the outer wall"`
+
+	// number of wall cells painted for a cue card, centered on its position along the wall
+	Width int `desc:"number of wall cells painted for a cue card, centered on its position along the wall"`
+
+	// current position, rotated from Home by LandmarkRot around the center of the world
+	Pos mat32.Vec2 `inactive:"+" desc:"current position, rotated from Home by LandmarkRot around the center of the world"`
+
+	// whether the landmark is currently present -- removed landmarks are not seen or painted
+	Active bool `inactive:"+" desc:"whether the landmark is currently present -- removed landmarks are not seen or painted"`
+
+	// cells currently painted with the cue card
+	Cells []evec.Vec2i `inactive:"+" desc:"cells currently painted with the cue card"`
+
+	// materials of the Cells before they were painted
+	Unders []int `inactive:"+" desc:"materials of the Cells before they were painted"`
+}
+
+// LandmarkEpisode has the changes made to the landmarks at the start of
+// each new episode, as in cue-rotation and cue-removal experiments
+type LandmarkEpisode struct {
+
+	// rotation in degrees of all the landmarks around the center of the world, at the start of each episode -- 0 = none
+	Rotate float32 `desc:"rotation in degrees of all the landmarks around the center of the world, at the start of each episode -- 0 = none"`
+
+	// rotate by a random multiple of Rotate (including 0) from Home, instead of rotating by Rotate each episode
+	RandRotate bool `desc:"rotate by a random multiple of Rotate (including 0) from Home, instead of rotating by Rotate each episode"`
+
+	// probability of removing each landmark for each episode -- otherwise it is present
+	RemoveP float32 `desc:"probability of removing each landmark for each episode -- otherwise it is present"`
+}
+
+// AddLandmark adds a new landmark with given name, cue card material (empty
+// for a distal landmark), home position and cue card width
+func (ev *FWorld) AddLandmark(name, mat string, home mat32.Vec2, width int) *Landmark {
+	lm := &Landmark{Name: name, Mat: mat, Home: home, Width: width, Pos: home, Active: true}
+	ev.Landmarks = append(ev.Landmarks, lm)
+	return lm
+}
+
+// ConfigLandmarks configures an example set of landmarks: a cue card on
+// the far (max Y) wall, with its CueCard material, and two distal landmarks
+// beyond the walls.  Must be called after the Size is set.
+func (ev *FWorld) ConfigLandmarks() {
+	ev.Landmarks = nil
+	if !ev.HasMat("CueCard") {
+		ev.Mats = append(ev.Mats, "CueCard")
+	}
+	ev.AddLandmark("CueCard", "CueCard", mat32.Vec2{float32(ev.Size.X / 2), float32(ev.Size.Y - 1)}, 9)
+	ev.AddLandmark("DistalW", "", mat32.Vec2{-0.2 * float32(ev.Size.X), 0.5 * float32(ev.Size.Y)}, 0)
+	ev.AddLandmark("DistalNE", "", mat32.Vec2{1.2 * float32(ev.Size.X), 1.2 * float32(ev.Size.Y)}, 0)
+}
+
+// ConfigLandmarksImpl does the automatic parts of landmark configuration
+func (ev *FWorld) ConfigLandmarksImpl() {
+	ev.LandmarkMats = make(map[int]bool)
+	for _, lm := range ev.Landmarks {
+		if mi, ok := ev.MatMap[lm.Mat]; ok {
+			ev.LandmarkMats[mi] = true
+		}
+	}
+}
+
+// ConfigLandmarkViewImpl configures the Landmarks state for the current agent,
+// if there are any Landmarks
+func (ev *FWorld) ConfigLandmarkViewImpl() {
+	if len(ev.Landmarks) == 0 {
+		delete(ev.NextStates, "Landmarks")
+		delete(ev.CurStates, "Landmarks")
+		return
+	}
+	ls := &etensor.Float32{}
+	ls.SetShape([]int{len(ev.Landmarks), 2, ev.PopSize}, nil, []string{"Landmark", "BearDist", "Pop"})
+	ev.NextStates["Landmarks"] = ls
+}
+
+// InitLandmarks puts all the landmarks back at their Home positions,
+// present, and paints the cue cards into the world.  Any previously painted
+// cells are forgotten, as the world is assumed to have been reloaded.
+func (ev *FWorld) InitLandmarks() {
+	ev.LandmarkRot = 0
+	for _, lm := range ev.Landmarks {
+		lm.Pos = lm.Home
+		lm.Active = true
+		lm.Cells = nil
+		lm.Unders = nil
+	}
+	ev.PaintLandmarks()
+}
+
+// UnpaintLandmarks restores the cells painted with cue cards to their
+// prior materials
+func (ev *FWorld) UnpaintLandmarks() {
+	for _, lm := range ev.Landmarks {
+		mi := ev.MatMap[lm.Mat]
+		for i, c := range lm.Cells {
+			if ev.GetWorld(c) == mi {
+				ev.ChangeWorld(c, lm.Unders[i])
+			}
+		}
+		lm.Cells = nil
+		lm.Unders = nil
+	}
+}
+
+// PaintLandmarks paints the cue cards of all active landmarks onto the
+// wall cells around their positions, along the direction of the wall
+func (ev *FWorld) PaintLandmarks() {
+	wall := ev.MatMap["Wall"]
+	for _, lm := range ev.Landmarks {
+		mi, ok := ev.MatMap[lm.Mat]
+		if !ok || !lm.Active {
+			continue
+		}
+		p := evec.NewVec2iFmVec2Round(lm.Pos)
+		dir := evec.Vec2i{1, 0}
+		if (p.X == 0 || p.X == ev.Size.X-1) && p.Y != 0 && p.Y != ev.Size.Y-1 {
+			dir = evec.Vec2i{0, 1}
+		}
+		hw := lm.Width / 2
+		for i := -hw; i < lm.Width-hw; i++ {
+			c := evec.Vec2i{p.X + i*dir.X, p.Y + i*dir.Y}
+			if !ev.InWorld(c) || ev.GetWorld(c) != wall {
+				continue
+			}
+			lm.Cells = append(lm.Cells, c)
+			lm.Unders = append(lm.Unders, wall)
+			ev.ChangeWorld(c, mi)
+		}
+	}
+}
+
+// SetLandmarkRot sets the rotation of all landmarks from their Home
+// positions, around the center of the world, in degrees.  Cue cards are
+// projected back onto the outer wall, and repainted.
+func (ev *FWorld) SetLandmarkRot(rot float32) {
+	ev.UnpaintLandmarks()
+	ev.LandmarkRot = rot
+	ctr := mat32.Vec2{float32(ev.Size.X-1) / 2, float32(ev.Size.Y-1) / 2}
+	a := mat32.DegToRad(rot)
+	cs, sn := mat32.Cos(a), mat32.Sin(a)
+	for _, lm := range ev.Landmarks {
+		d := lm.Home.Sub(ctr)
+		d = mat32.Vec2{cs*d.X - sn*d.Y, sn*d.X + cs*d.Y}
+		if lm.Mat != "" { // project onto outer wall
+			sc := mat32.Max(mat32.Abs(d.X)/ctr.X, mat32.Abs(d.Y)/ctr.Y)
+			if sc > 0 {
+				d = d.DivScalar(sc)
+			}
+		}
+		lm.Pos = ctr.Add(d)
+	}
+	ev.PaintLandmarks()
+}
+
+// SetLandmarkActive sets whether given landmark is present, and repaints
+func (ev *FWorld) SetLandmarkActive(name string, active bool) error {
+	for _, lm := range ev.Landmarks {
+		if lm.Name == name {
+			ev.UnpaintLandmarks()
+			lm.Active = active
+			ev.PaintLandmarks()
+			return nil
+		}
+	}
+	return fmt.Errorf("FWorld SetLandmarkActive: landmark named: %s not found", name)
+}
+
+// LandmarksNewEpisode makes the LandmarkEp changes to the landmarks for a
+// new episode
+func (ev *FWorld) LandmarksNewEpisode() {
+	le := &ev.LandmarkEp
+	if le.RemoveP > 0 {
+		ev.UnpaintLandmarks()
+		for _, lm := range ev.Landmarks {
+			lm.Active = !erand.BoolP32(le.RemoveP, -1, &ev.Rand)
+		}
+		ev.PaintLandmarks()
+	}
+	if le.Rotate != 0 {
+		if le.RandRotate {
+			n := int(mat32.Abs(360 / le.Rotate))
+			if n < 1 {
+				n = 1
+			}
+			ev.SetLandmarkRot(le.Rotate * float32(ev.Rand.Intn(n, -1)))
+		} else {
+			ev.SetLandmarkRot(ev.LandmarkRot + le.Rotate)
+		}
+	}
+}
+
+// LandmarkBearDist returns the bearing of given landmark relative to the
+// current agent's heading, in degrees (-180..180, positive = left),
+// and its distance
+func (ev *FWorld) LandmarkBearDist(lm *Landmark) (bear, dist float32) {
+	d := lm.Pos.Sub(ev.PosF)
+	dist = d.Length()
+	head := float32(ev.Angle)
+	if ev.Continuous {
+		head = ev.AngleF
+	}
+	bear = mat32.RadToDeg(mat32.Atan2(d.Y, d.X)) - head
+	for bear > 180 {
+		bear -= 360
+	}
+	for bear < -180 {
+		bear += 360
+	}
+	return
+}
+
+// RenderLandmarks renders the Landmarks view: for each landmark, the
+// bearing relative to heading, as a ring pop code that wraps around
+// behind the agent, and the log distance, as pop codes, or
+// no activity if it is removed, outside of the LandmarkFOV, or beyond
+// the current visual range
+func (ev *FWorld) RenderLandmarks() {
+	ls, ok := ev.NextStates["Landmarks"]
+	if !ok {
+		return
+	}
+	ls.SetZeros()
+	hfov := 0.5 * ev.Params["LandmarkFOV"]
+	maxld := mat32.Log(1 + 2*mat32.Sqrt(float32(ev.Size.X*ev.Size.X+ev.Size.Y*ev.Size.Y)))
+	for i, lm := range ev.Landmarks {
+		if !lm.Active {
+			continue
+		}
+		bear, dist := ev.LandmarkBearDist(lm)
+		if mat32.Abs(bear) > hfov || !ev.InVisRange(dist) {
+			continue
+		}
+		sv := ls.SubSpace([]int{i, 0}).(*etensor.Float32)
+		ev.AngCode.Encode(&sv.Values, 0.5+bear/360, ev.PopSize)
+		sv = ls.SubSpace([]int{i, 1}).(*etensor.Float32)
+		ev.PopCode.Encode(&sv.Values, mat32.Min(mat32.Log(1+dist)/maxld, 1), ev.PopSize, false)
+	}
+}
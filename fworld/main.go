@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// main for GUI interaction with Env for testing
+// main for GUI interaction with Env for testing -- runs headless with -nogui (see CmdArgs)
 package main
 
 import (
@@ -20,6 +20,9 @@ import (
 )
 
 func main() {
+	if TheSim.CmdArgs() { // ran with -nogui
+		return
+	}
 	gimain.Main(func() { // this starts gui -- requires valid OpenGL display connection (e.g., X11)
 		guirun()
 	})
@@ -82,13 +85,16 @@ var TheSim Sim
 
 // Config configures all the elements using the standard functions
 func (ss *Sim) Config() {
-	// order: Empty, wall, food, water
-	ss.MatColors = []string{"lightgrey", "black", "orange", "blue", "brown", "navy"}
-
 	ss.StepN = 10
 	ss.World.Config(1000)
 	ss.World.Init(0)
 
+	colors := map[string]string{"Empty": "lightgrey", "Wall": "black", "Food": "orange", "Water": "blue", "FoodWas": "brown", "WaterWas": "navy", "Agent": "purple", "CueCard": "yellow", "Prey": "green", "Predator": "red"}
+	ss.MatColors = make([]string, len(ss.World.Mats))
+	for i, m := range ss.World.Mats {
+		ss.MatColors[i] = colors[m]
+	}
+
 	ss.Trace = ss.World.World.Clone().(*etensor.Int)
 
 	sch := etable.Schema{
@@ -99,8 +105,20 @@ func (ss *Sim) Config() {
 		{"ProxSoma", etensor.FLOAT32, ss.World.CurStates["ProxSoma"].Shape.Shp, nil},
 		{"Vestibular", etensor.FLOAT32, ss.World.CurStates["Vestibular"].Shape.Shp, nil},
 		{"Inters", etensor.FLOAT32, ss.World.CurStates["Inters"].Shape.Shp, nil},
+		{"Olfact", etensor.FLOAT32, ss.World.CurStates["Olfact"].Shape.Shp, nil},
+		{"Urgency", etensor.FLOAT32, ss.World.CurStates["Urgency"].Shape.Shp, nil},
+		{"Clock", etensor.FLOAT32, ss.World.CurStates["Clock"].Shape.Shp, nil},
 		{"Action", etensor.FLOAT32, ss.World.CurStates["Action"].Shape.Shp, nil},
 	}
+	if st, ok := ss.World.CurStates["Landmarks"]; ok {
+		sch = append(sch, etable.Column{"Landmarks", etensor.FLOAT32, st.Shape.Shp, nil})
+	}
+	for _, ey := range ss.World.Eyes {
+		for _, st := range []string{"Depth", "FovDepth", "Fovea"} {
+			snm := st + ey.Name
+			sch = append(sch, etable.Column{snm, etensor.FLOAT32, ss.World.CurStates[snm].Shape.Shp, nil})
+		}
+	}
 	ss.State = etable.NewTable("input")
 	ss.State.SetFromSchema(sch, 1)
 	ss.State.SetMetaData("TrialName:width", "50")
@@ -124,19 +142,25 @@ func (ss *Sim) Step() {
 	}
 	ss.State.SetCellString("TrialName", 0, ss.World.String())
 
-	if ss.World.Scene.Chg { // something important happened, refresh
+	if ss.World.Scene.Chg || ss.World.Episode.Chg { // something important happened, refresh
 		ss.Trace.CopyFrom(ss.World.World)
 	}
 
 	nc := len(ss.World.Mats)
-	ss.Trace.Set([]int{ss.World.PosI.Y, ss.World.PosI.X}, nc+ss.World.Angle/ss.World.AngInc)
+	for _, ag := range ss.World.Agents {
+		ss.Trace.Set([]int{ag.PosI.Y, ag.PosI.X}, nc+ag.Angle/ss.World.AngInc)
+	}
 
 	ss.UpdtViews()
 }
 
 func (ss *Sim) StepAuto() {
-	act := ss.World.ActGen()
-	ss.World.Action(ss.World.Acts[act], nil)
+	if len(ss.World.Agents) > 1 {
+		ss.World.ActionAgents(ss.World.ActGenAgents())
+	} else {
+		act := ss.World.ActGen()
+		ss.World.Action(ss.World.Acts[act], nil)
+	}
 	ss.Step()
 }
 
@@ -0,0 +1,117 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sort"
+
+	"github.com/emer/emergent/erand"
+	"github.com/goki/mat32"
+)
+
+// SensorNoise has the parameters for noise in the sensory states, which is
+// applied when rendering the states, so that the underlying scanned state
+// (Depths, FovMats etc) remains exact.  All zero values = no noise.
+// Noise uses the world Rand, so recorded episodes replay exactly.
+type SensorNoise struct {
+
+	// standard deviation of Gaussian noise added to the normalized log depth of each Depth and FovDepth ray
+	DepthSD float32 `desc:"standard deviation of Gaussian noise added to the normalized log depth of each Depth and FovDepth ray"`
+
+	// probability of dropout of each Depth and fovea ray, which is then rendered with no activity
+	DropP float32 `desc:"probability of dropout of each Depth and fovea ray, which is then rendered with no activity"`
+
+	// material confusion matrix: for each true material, the probability of misidentifying it as each other material in the Fovea -- see SetConfusion
+	Confusion map[string]map[string]float32 `desc:"material confusion matrix: for each true material, the probability of misidentifying it as each other material in the Fovea -- see SetConfusion"`
+
+	// maximum distance in cells at which materials can be identified in the Fovea -- beyond this they are rendered as Empty, while FovDepth is still rendered -- 0 = unlimited
+	FovRange float32 `desc:"maximum distance in cells at which materials can be identified in the Fovea -- beyond this they are rendered as Empty, while FovDepth is still rendered -- 0 = unlimited"`
+
+	// standard deviation of the random walk increments of the vestibular drift, which is added to the Vestibular signals in normalized units
+	VestDriftSD float32 `desc:"standard deviation of the random walk increments of the vestibular drift, which is added to the Vestibular signals in normalized units"`
+
+	// maximum absolute value of the vestibular drift
+	VestDriftMax float32 `desc:"maximum absolute value of the vestibular drift"`
+}
+
+// Defaults sets the default values: no noise
+func (sn *SensorNoise) Defaults() {
+	sn.DepthSD = 0
+	sn.DropP = 0
+	sn.Confusion = make(map[string]map[string]float32)
+	sn.FovRange = 0
+	sn.VestDriftSD = 0
+	sn.VestDriftMax = 0.2
+}
+
+// SetConfusion sets the probability of misidentifying material mat as other
+func (sn *SensorNoise) SetConfusion(mat, other string, p float32) {
+	if sn.Confusion == nil {
+		sn.Confusion = make(map[string]map[string]float32)
+	}
+	cm, ok := sn.Confusion[mat]
+	if !ok {
+		cm = make(map[string]float32)
+		sn.Confusion[mat] = cm
+	}
+	cm[other] = p
+}
+
+// NoiseDepth returns given normalized log depth with Gaussian DepthSD noise
+func (ev *FWorld) NoiseDepth(dl float32) float32 {
+	if ev.Noise.DepthSD <= 0 {
+		return dl
+	}
+	dl += ev.Noise.DepthSD * float32(ev.Rand.NormFloat64(-1))
+	return mat32.Clamp(dl, 0, 1)
+}
+
+// NoiseDrop returns true if a ray is dropped out, with probability DropP
+func (ev *FWorld) NoiseDrop() bool {
+	if ev.Noise.DropP <= 0 {
+		return false
+	}
+	return erand.BoolP32(ev.Noise.DropP, -1, &ev.Rand)
+}
+
+// NoiseMat returns the material that is perceived in the fovea for given
+// true material at given depth, according to the FovRange and Confusion
+func (ev *FWorld) NoiseMat(mat int, depth float32) int {
+	if ev.Noise.FovRange > 0 && (depth < 0 || depth > ev.Noise.FovRange) {
+		return 0
+	}
+	cm, ok := ev.Noise.Confusion[ev.MatName(mat)]
+	if !ok || len(cm) == 0 {
+		return mat
+	}
+	others := make([]string, 0, len(cm))
+	for k := range cm {
+		others = append(others, k)
+	}
+	sort.Strings(others) // reproducible order
+	r := ev.Rand.Float32(-1)
+	cum := float32(0)
+	for _, om := range others {
+		cum += cm[om]
+		if r < cum {
+			if oi, ok := ev.MatMap[om]; ok {
+				return oi
+			}
+			return mat
+		}
+	}
+	return mat
+}
+
+// UpdateVestDrift updates the vestibular drift of the current agent
+// by a random walk step
+func (ev *FWorld) UpdateVestDrift() {
+	if ev.Noise.VestDriftSD <= 0 {
+		return
+	}
+	mx := ev.Noise.VestDriftMax
+	ev.VestDrift += ev.Noise.VestDriftSD * float32(ev.Rand.NormFloat64(-1))
+	ev.VestDrift = mat32.Clamp(ev.VestDrift, -mx, mx)
+}
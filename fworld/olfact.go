@@ -0,0 +1,165 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/emer/emergent/evec"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/mat32"
+)
+
+// MatOdor specifies the odor emitted by a material
+type MatOdor struct {
+
+	// type of odor emitted -- must be in FWorld.Odors
+	Odor string `desc:"type of odor emitted -- must be in FWorld.Odors"`
+
+	// concentration of odor at the source
+	Strength float32 `desc:"concentration of odor at the source"`
+
+	// multiplicative decay of concentration per cell of distance from the source, diffusing around barriers -- e.g., .9
+	Decay float32 `desc:"multiplicative decay of concentration per cell of distance from the source, diffusing around barriers -- e.g., .9"`
+}
+
+// ConfigOdors configures the default odors emitted by materials
+func (ev *FWorld) ConfigOdors() {
+	ev.Odors = []string{"Food", "Water"}
+	ev.MatOdors = map[string]*MatOdor{
+		"Food":  {Odor: "Food", Strength: 1, Decay: 0.9},
+		"Water": {Odor: "Water", Strength: 1, Decay: 0.9},
+	}
+	ev.Params["OdorMin"] = 0.01  // minimum concentration diffused from a source
+	ev.Params["OdorHalf"] = 0.5  // concentration producing half-maximal sensory response
+	ev.Params["NostrilAng"] = 45 // angle of nostrils relative to heading, in degrees
+}
+
+// ConfigOdorsImpl does the automatic parts of odor configuration
+func (ev *FWorld) ConfigOdorsImpl() {
+	ev.OdorMap = make(map[string]int, len(ev.Odors))
+	for i, o := range ev.Odors {
+		ev.OdorMap[o] = i
+	}
+	ev.OdorFields = make(map[string]*etensor.Float32, len(ev.Odors))
+	for _, o := range ev.Odors {
+		of := &etensor.Float32{}
+		of.SetShape([]int{ev.Size.Y, ev.Size.X}, nil, []string{"Y", "X"})
+		ev.OdorFields[o] = of
+	}
+}
+
+// ConfigOlfactImpl configures the odor sensing and Olfact state for the current agent
+func (ev *FWorld) ConfigOlfactImpl() {
+	no := len(ev.Odors)
+	ev.OdorConcs = make([]float32, no)
+	ev.OdorLRs = make([]float32, no)
+
+	ol := &etensor.Float32{}
+	ol.SetShape([]int{2, no, ev.PopSize, 1}, nil, []string{"ConcLR", "Odor", "Pop", "1"})
+	ev.NextStates["Olfact"] = ol
+}
+
+// InitOdors recomputes all the odor fields from the materials in the world
+func (ev *FWorld) InitOdors() {
+	for _, of := range ev.OdorFields {
+		of.SetZeros()
+	}
+	for y := 0; y < ev.Size.Y; y++ {
+		for x := 0; x < ev.Size.X; x++ {
+			p := evec.Vec2i{x, y}
+			ev.OdorSource(p, ev.GetWorld(p), 1)
+		}
+	}
+}
+
+// OdorSource adds (sign = 1) or removes (sign = -1) the odor emitted by
+// given material at given position to its odor field, diffusing outward
+// around barriers with multiplicative decay per cell, until the
+// concentration falls below OdorMin.  Removing only cancels the odor that
+// was added if the barriers have not changed since -- see ChangeWorld.
+func (ev *FWorld) OdorSource(pos evec.Vec2i, mat int, sign float32) {
+	if mat <= 0 || mat >= len(ev.Mats) {
+		return
+	}
+	mo, ok := ev.MatOdors[ev.Mats[mat]]
+	if !ok || mo.Strength == 0 {
+		return
+	}
+	of, ok := ev.OdorFields[mo.Odor]
+	if !ok {
+		return
+	}
+	minc := ev.Params["OdorMin"]
+	visited := make([]bool, ev.Size.X*ev.Size.Y)
+	visited[pos.Y*ev.Size.X+pos.X] = true
+	front := []evec.Vec2i{pos}
+	conc := mo.Strength
+	for len(front) > 0 && conc >= minc {
+		var next []evec.Vec2i
+		for _, p := range front {
+			of.Values[p.Y*ev.Size.X+p.X] += sign * conc
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					np := evec.Vec2i{p.X + dx, p.Y + dy}
+					if !ev.InWorld(np) {
+						continue
+					}
+					ni := np.Y*ev.Size.X + np.X
+					if visited[ni] {
+						continue
+					}
+					visited[ni] = true
+					if ev.IsBarrier(ev.GetWorld(np)) {
+						continue
+					}
+					next = append(next, np)
+				}
+			}
+		}
+		front = next
+		conc *= mo.Decay
+	}
+}
+
+// OdorAt returns the concentration of given odor type at given point
+func (ev *FWorld) OdorAt(odor string, p evec.Vec2i) float32 {
+	of, ok := ev.OdorFields[odor]
+	if !ok || !ev.InWorld(p) {
+		return 0
+	}
+	return of.Values[p.Y*ev.Size.X+p.X]
+}
+
+// ScanOdor senses the odor concentrations at the nose (directly in front)
+// and the left - right difference between the two nostrils
+func (ev *FWorld) ScanOdor() {
+	nang := int(ev.Params["NostrilAng"])
+	_, np := NextVecPoint(ev.PosF, AngVec(ev.Angle))
+	_, lp := NextVecPoint(ev.PosF, AngVec(ev.Angle+nang))
+	_, rp := NextVecPoint(ev.PosF, AngVec(ev.Angle-nang))
+	for i, o := range ev.Odors {
+		ev.OdorConcs[i] = ev.OdorAt(o, np)
+		lc := ev.OdorAt(o, lp)
+		rc := ev.OdorAt(o, rp)
+		if lc+rc > 0 {
+			ev.OdorLRs[i] = (lc - rc) / (lc + rc)
+		} else {
+			ev.OdorLRs[i] = 0
+		}
+	}
+}
+
+// RenderOlfact renders olfactory state: saturating concentration at the nose,
+// and the left - right difference, where > .5 = stronger on the left
+func (ev *FWorld) RenderOlfact() {
+	ol := ev.NextStates["Olfact"]
+	hc := ev.Params["OdorHalf"]
+	for i := range ev.Odors {
+		c := mat32.Max(ev.OdorConcs[i], 0)
+		sv := ol.SubSpace([]int{0, i}).(*etensor.Float32)
+		ev.PopCode.Encode(&sv.Values, c/(c+hc), ev.PopSize, false)
+		sv = ol.SubSpace([]int{1, i}).(*etensor.Float32)
+		ev.PopCode.Encode(&sv.Values, 0.5+0.5*ev.OdorLRs[i], ev.PopSize, false)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/emer/emergent/evec"
+	"github.com/goki/mat32"
+)
+
+// testEmptyWorld returns a test world with nothing in it, and no odors
+func testEmptyWorld(t *testing.T) *FWorld {
+	ev := testWorld(t)
+	ev.World.SetZeros()
+	ev.InitOdors()
+	return ev
+}
+
+// checkOdors checks that the odor fields are the same as recomputed by InitOdors
+func checkOdors(t *testing.T, ev *FWorld) {
+	ofs := CopyStateMap(ev.OdorFields)
+	ev.InitOdors()
+	for o, of := range ofs {
+		for i, v := range of.Values {
+			if mat32.Abs(v-ev.OdorFields[o].Values[i]) > 1.0e-5 {
+				t.Fatalf("odor: %s at: %d: %g != recomputed: %g", o, i, v, ev.OdorFields[o].Values[i])
+			}
+		}
+	}
+}
+
+func TestOdorBarrier(t *testing.T) {
+	ev := testEmptyWorld(t)
+	food := ev.MatMap["Food"]
+	wall := ev.MatMap["Wall"]
+	ctr := evec.Vec2i{50, 50}
+	out := evec.Vec2i{50, 53}
+	gap := evec.Vec2i{50, 52}
+	ev.WorldRect(evec.Vec2i{48, 48}, evec.Vec2i{52, 52}, wall)
+	ev.InitOdors()
+	ev.ChangeWorld(ctr, food)
+	if c := ev.OdorAt("Food", ctr); c != ev.MatOdors["Food"].Strength {
+		t.Errorf("odor at the source: %g != Strength", c)
+	}
+	if c := ev.OdorAt("Food", out); c != 0 {
+		t.Errorf("odor outside the walls: %g", c)
+	}
+	ev.ChangeWorld(gap, 0)
+	if c := ev.OdorAt("Food", out); c <= 0 {
+		t.Errorf("no odor through the gap in the walls")
+	}
+	checkOdors(t, ev)
+	ev.ChangeWorld(gap, wall)
+	ev.ChangeWorld(ctr, 0)
+	ev.ChangeWorld(gap, 0)
+	for _, v := range ev.OdorFields["Food"].Values {
+		if mat32.Abs(v) > 1.0e-5 {
+			t.Fatalf("residual odor: %g after the source was removed", v)
+		}
+	}
+}
+
+func TestOdorConsume(t *testing.T) {
+	ev := testWorld(t)
+	ev.SetParams(map[string]float32{"FoodRefresh": 5, "WaterRefresh": 5})
+	ev.ConfigImpl()
+	ev.Init(0)
+	for i := 0; i < 500; i++ {
+		ev.Action(ev.Acts[ev.ActGen()], nil)
+		ev.Step()
+	}
+	if len(ev.AllEvents) == 0 {
+		t.Fatal("nothing consumed")
+	}
+	checkOdors(t, ev)
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/emer/emergent/erand"
+	"github.com/emer/etable/etensor"
+	"github.com/emer/etable/metric"
+)
+
+// PatGenParams has the parameters for automatically generating the
+// bit patterns for materials and actions that are not in the pats.json file.
+// Patterns are either random sparse patterns with a minimum difference from
+// all other patterns (as in patgen.PermutedBinaryMinDiff), or composed from
+// shared feature patterns, so that related materials have overlapping patterns.
+type PatGenParams struct {
+
+	// generate all patterns, ignoring any in the pats.json file -- otherwise only those that are missing are generated
+	All bool `desc:"generate all patterns, ignoring any in the pats.json file -- otherwise only those that are missing are generated"`
+
+	// save the patterns back to the pats.json file if any were generated -- turn on to regenerate pats.json
+	Save bool `desc:"save the patterns back to the pats.json file if any were generated -- turn on to regenerate pats.json"`
+
+	// number of bits on in random sparse patterns
+	NOn int `desc:"number of bits on in random sparse patterns"`
+
+	// minimum number of bits that must be different between a random sparse pattern and all other patterns (can't be > NOn)
+	MinDiff int `desc:"minimum number of bits that must be different between a random sparse pattern and all other patterns (can't be > NOn)"`
+
+	// number of bits on in each feature pattern
+	FeatNOn int `desc:"number of bits on in each feature pattern"`
+
+	// features that compose the pattern of each material or action, e.g., Food = FoodType + Present, FoodWas = FoodType + Absent, so that related ones share bits -- names not in the map get random sparse patterns
+	Features map[string][]string `desc:"features that compose the pattern of each material or action, e.g., Food = FoodType + Present, FoodWas = FoodType + Absent, so that related ones share bits -- names not in the map get random sparse patterns"`
+
+	// random seed for generating patterns
+	Seed int64 `desc:"random seed for generating patterns"`
+}
+
+// Defaults sets the default parameters, with features for the consumables
+// and their used-up versions
+func (pg *PatGenParams) Defaults() {
+	pg.All = false
+	pg.Save = false
+	pg.NOn = 6
+	pg.MinDiff = 3
+	pg.FeatNOn = 3
+	pg.Features = map[string][]string{
+		"Food":     {"FoodType", "Present"},
+		"FoodWas":  {"FoodType", "Absent"},
+		"Water":    {"WaterType", "Present"},
+		"WaterWas": {"WaterType", "Absent"},
+	}
+	pg.Seed = 1
+}
+
+// NewPat returns a new empty pattern of PatSize
+func (ev *FWorld) NewPat() *etensor.Float32 {
+	t := &etensor.Float32{}
+	t.SetShape([]int{ev.PatSize.Y, ev.PatSize.X}, nil, []string{"Y", "X"})
+	return t
+}
+
+// PatDiff returns the number of bits that are different between two
+// patterns, as half the Hamming distance (as in patgen)
+func PatDiff(a, b *etensor.Float32) int {
+	return int(math.Round(float64(.5 * metric.Hamming32(a.Values, b.Values))))
+}
+
+// GenPats generates patterns for all the materials and actions that do not
+// have a valid pattern in Pats (or all if PatGen.All), returning the names
+// of those generated.  Empty is always all zeros.  Returns an error if
+// any pattern could not be generated with its minimum difference.
+func (ev *FWorld) GenPats() ([]string, error) {
+	pg := &ev.PatGen
+	var names []string
+	for _, nm := range append(append([]string{}, ev.Mats...), ev.Acts...) {
+		pt, has := ev.Pats[nm]
+		if pg.All || !has || pt.Len() != ev.PatSize.X*ev.PatSize.Y {
+			names = append(names, nm)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	if pg.All {
+		ev.Pats = make(map[string]*etensor.Float32)
+	}
+	var rnd erand.SysRand
+	rnd.NewRand(pg.Seed)
+
+	feats := make(map[string]*etensor.Float32)
+	var fnms []string
+	for _, nm := range names {
+		for _, fn := range pg.Features[nm] {
+			if _, has := feats[fn]; !has {
+				feats[fn] = nil
+				fnms = append(fnms, fn)
+			}
+		}
+	}
+	sort.Strings(fnms) // reproducible order
+	fpats := []*etensor.Float32{}
+	for _, fn := range fnms {
+		fp, err := ev.RandPat(&rnd, pg.FeatNOn, pg.FeatNOn, fpats) // features do not overlap
+		if err != nil {
+			return names, fmt.Errorf("GenPats: feature: %s: %v", fn, err)
+		}
+		feats[fn] = fp
+		fpats = append(fpats, fp)
+	}
+
+	for _, nm := range names {
+		if nm == "Empty" {
+			ev.Pats[nm] = ev.NewPat()
+			continue
+		}
+		fl, has := pg.Features[nm]
+		if !has {
+			continue
+		}
+		pt := ev.NewPat()
+		for _, fn := range fl {
+			for i, v := range feats[fn].Values {
+				if v > 0 {
+					pt.Values[i] = 1
+				}
+			}
+		}
+		ev.Pats[nm] = pt
+	}
+	for _, nm := range names {
+		if _, has := ev.Pats[nm]; has && (nm == "Empty" || len(pg.Features[nm]) > 0) {
+			continue
+		}
+		others := make([]*etensor.Float32, 0, len(ev.Pats))
+		for _, on := range append(append([]string{}, ev.Mats...), ev.Acts...) {
+			if op, has := ev.Pats[on]; has && on != "Empty" && op.Len() == ev.PatSize.X*ev.PatSize.Y {
+				others = append(others, op)
+			}
+		}
+		pt, err := ev.RandPat(&rnd, pg.NOn, pg.MinDiff, others)
+		if err != nil {
+			return names, fmt.Errorf("GenPats: %s: %v", nm, err)
+		}
+		ev.Pats[nm] = pt
+	}
+	return names, nil
+}
+
+// RandPat returns a random sparse pattern with nOn bits on, with at least
+// minDiff bits different from all of the others -- returns an error if
+// that is not found within a reasonable number of iterations, in which
+// case the PatSize or nOn should be increased.
+func (ev *FWorld) RandPat(rnd *erand.SysRand, nOn, minDiff int, others []*etensor.Float32) (*etensor.Float32, error) {
+	bestd := -1
+	for itr := 0; itr < 1000; itr++ {
+		pt := ev.NewPat()
+		pord := rnd.Perm(pt.Len(), -1)
+		for i := 0; i < nOn && i < len(pord); i++ {
+			pt.Values[pord[i]] = 1
+		}
+		mind := pt.Len()
+		for _, op := range others {
+			if d := PatDiff(pt, op); d < mind {
+				mind = d
+			}
+		}
+		if mind >= minDiff {
+			return pt, nil
+		}
+		if mind > bestd {
+			bestd = mind
+		}
+	}
+	return nil, fmt.Errorf("RandPat: minimum difference of: %d from %d other patterns was not met with %d bits on in PatSize: %v -- best was: %d", minDiff, len(others), nOn, ev.PatSize, bestd)
+}
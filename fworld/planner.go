@@ -0,0 +1,344 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"container/heap"
+
+	"github.com/emer/emergent/evec"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/mat32"
+)
+
+// PlanState is one state in the planning search: position and heading
+type PlanState struct {
+
+	// floating point position, as computed for the agent by NextVecPoint
+	PosF mat32.Vec2 `desc:"floating point position, as computed for the agent by NextVecPoint"`
+
+	// grid position
+	PosI evec.Vec2i `desc:"grid position"`
+
+	// heading angle, in degrees, 0 <= Angle < 360
+	Angle int `desc:"heading angle, in degrees, 0 <= Angle < 360"`
+}
+
+// planKey is the key for a PlanState in the search maps, with the
+// floating point position quantized to avoid numerical duplicates
+type planKey struct {
+	x, y, ang int
+}
+
+// Key returns the search key for this state
+func (st *PlanState) Key() planKey {
+	return planKey{int(mat32.Round(st.PosF.X * 1000)), int(mat32.Round(st.PosF.Y * 1000)), st.Angle}
+}
+
+// planNode is a search node in the planning priority queue
+type planNode struct {
+	st   PlanState
+	cost float32 // cost so far
+	est  float32 // cost so far + heuristic estimate of remaining cost
+}
+
+// planQueue is a priority queue of planNodes, lowest est first
+type planQueue []*planNode
+
+func (pq planQueue) Len() int            { return len(pq) }
+func (pq planQueue) Less(i, j int) bool  { return pq[i].est < pq[j].est }
+func (pq planQueue) Swap(i, j int)       { pq[i], pq[j] = pq[j], pq[i] }
+func (pq *planQueue) Push(x interface{}) { *pq = append(*pq, x.(*planNode)) }
+func (pq *planQueue) Pop() interface{} {
+	old := *pq
+	n := len(old)
+	nd := old[n-1]
+	*pq = old[:n-1]
+	return nd
+}
+
+// PlanCache is a plan computed by PlanTo, which is reused on subsequent
+// calls as long as the world is the same and the agent is still on the
+// planned path, so that OracleAct does not search again on every tick
+type PlanCache struct {
+
+	// material that the plan goes to
+	Mat string `desc:"material that the plan goes to"`
+
+	// PlanTurnCost param when planned
+	TurnCost float32 `desc:"PlanTurnCost param when planned"`
+
+	// world values when planned, with other agents marked as -1 -- see PlanWorld
+	World []int `desc:"world values when planned, with other agents marked as -1 -- see PlanWorld"`
+
+	// key of the state before each action, or just the start state if no path was found
+	States []planKey `desc:"key of the state before each action, or just the start state if no path was found"`
+
+	// planned actions -- nil if no path was found
+	Acts []int `desc:"planned actions -- nil if no path was found"`
+
+	// remaining cost from the state before each action, or just -1 if no path was found
+	Costs []float32 `desc:"remaining cost from the state before each action, or just -1 if no path was found"`
+}
+
+// PlanWorld returns a copy of the world values as used for planning,
+// with the cells of agents other than the current one marked as -1
+func (ev *FWorld) PlanWorld() []int {
+	wv := append([]int{}, ev.World.Values...)
+	for _, ag := range ev.Agents {
+		if ag != ev.Agent && ev.InWorld(ag.PosI) {
+			wv[ag.PosI.Y*ev.Size.X+ag.PosI.X] = -1
+		}
+	}
+	return wv
+}
+
+// CachedPlan returns the remaining actions and cost of the cached plan
+// of the current agent to given material, if the world and PlanTurnCost
+// have not changed since it was planned, and the agent is at given state
+// on the path
+func (ev *FWorld) CachedPlan(mat string, skey planKey, wv []int) ([]int, float32, bool) {
+	pc, ok := ev.Plans[ev.ID]
+	if !ok || pc.Mat != mat || pc.TurnCost != ev.Params["PlanTurnCost"] || len(pc.World) != len(wv) {
+		return nil, -1, false
+	}
+	for i, v := range wv {
+		if pc.World[i] != v {
+			return nil, -1, false
+		}
+	}
+	for i, k := range pc.States {
+		if k == skey {
+			if pc.Acts == nil {
+				return nil, -1, true
+			}
+			return pc.Acts[i:], pc.Costs[i], true
+		}
+	}
+	return nil, -1, false
+}
+
+// ConfigPlanner configures the default planner parameters
+func (ev *FWorld) ConfigPlanner() {
+	ev.Params["PlanTurnCost"] = 1       // cost of a Left or Right turn in planning, relative to 1 for Forward or Backward
+	ev.Params["PlanMaxStates"] = 200000 // maximum number of states to search before giving up
+}
+
+// DistField returns the distance field for given material: for each cell,
+// the minimum number of moves (8-connected, around barriers and other agents)
+// to a cell containing the material, which are 0, with -1 for unreachable
+// cells and barriers.
+func (ev *FWorld) DistField(mat string) *etensor.Float32 {
+	df := &etensor.Float32{}
+	df.SetShape([]int{ev.Size.Y, ev.Size.X}, nil, []string{"Y", "X"})
+	for i := range df.Values {
+		df.Values[i] = -1
+	}
+	mi, ok := ev.MatMap[mat]
+	if !ok {
+		return df
+	}
+	var front []evec.Vec2i
+	for y := 0; y < ev.Size.Y; y++ {
+		for x := 0; x < ev.Size.X; x++ {
+			p := evec.Vec2i{x, y}
+			if ev.GetWorld(p) == mi {
+				df.Values[y*ev.Size.X+x] = 0
+				front = append(front, p)
+			}
+		}
+	}
+	dist := float32(0)
+	for len(front) > 0 {
+		dist++
+		var next []evec.Vec2i
+		for _, p := range front {
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					np := evec.Vec2i{p.X + dx, p.Y + dy}
+					if !ev.InWorld(np) {
+						continue
+					}
+					ni := np.Y*ev.Size.X + np.X
+					if df.Values[ni] >= 0 || ev.IsBarrier(ev.MatAt(np)) {
+						continue
+					}
+					df.Values[ni] = dist
+					next = append(next, np)
+				}
+			}
+		}
+		front = next
+	}
+	return df
+}
+
+// PlanNext returns the state resulting from taking given action
+// in given state, and whether the action is possible (not blocked)
+func (ev *FWorld) PlanNext(st PlanState, act string) (PlanState, bool) {
+	switch act {
+	case "Left":
+		st.Angle = (st.Angle + ev.AngInc) % 360
+	case "Right":
+		st.Angle = (st.Angle + 360 - ev.AngInc) % 360
+	case "Forward", "Backward":
+		ang := st.Angle
+		if act == "Backward" {
+			ang = (ang + 180) % 360
+		}
+		nf, np := NextVecPoint(st.PosF, AngVec(ang))
+		if !ev.InWorld(np) || ev.IsBarrier(ev.MatAt(np)) {
+			return st, false
+		}
+		st.PosF, st.PosI = nf, np
+	default:
+		return st, false
+	}
+	return st, true
+}
+
+// PlanTo returns the shortest sequence of movement actions (Left, Right,
+// Forward, Backward) from the current agent's position and heading, to
+// facing the nearest instance of given material in the adjacent front cell,
+// along with the total cost (PlanTurnCost for each turn, 1 for each move),
+// using A* search with the DistField as the heuristic.
+// The search tracks the exact floating point position that the agent
+// would have after each move, so the sequence can be executed as is in the
+// discrete mode, as long as nothing else changes in the world.
+// Returns nil, -1 if there is no such path within PlanMaxStates searched,
+// or right away if the DistField shows that the material is unreachable.
+// An empty sequence means that the agent is already facing the material.
+// The plan is cached for each agent (see PlanCache), so the search is only
+// done again when the world or PlanTurnCost changes, or the agent leaves
+// the planned path.
+func (ev *FWorld) PlanTo(mat string) ([]int, float32) {
+	mi, ok := ev.MatMap[mat]
+	if !ok {
+		return nil, -1
+	}
+	if ev.Plans == nil {
+		ev.Plans = make(map[string]*PlanCache)
+	}
+	start := PlanState{ev.PosF, ev.PosI, ev.Angle % 360}
+	skey := start.Key()
+	wv := ev.PlanWorld()
+	if acts, cost, ok := ev.CachedPlan(mat, skey, wv); ok {
+		return acts, cost
+	}
+	pc := &PlanCache{Mat: mat, TurnCost: ev.Params["PlanTurnCost"], World: wv, States: []planKey{skey}, Costs: []float32{-1}}
+	ev.Plans[ev.ID] = pc
+	df := ev.DistField(mat)
+	if df.Values[ev.PosI.Y*ev.Size.X+ev.PosI.X] < 0 { // unreachable
+		return nil, -1
+	}
+	tc := ev.Params["PlanTurnCost"]
+	heur := func(st PlanState) float32 {
+		d := df.Values[st.PosI.Y*ev.Size.X+st.PosI.X]
+		if d <= 1 {
+			return 0
+		}
+		return d - 1
+	}
+	goal := func(st PlanState) bool {
+		_, fp := NextVecPoint(st.PosF, AngVec(st.Angle))
+		return ev.InWorld(fp) && ev.GetWorld(fp) == mi && ev.AgentAt(fp, ev.Agent) == nil
+	}
+	mvacts := []string{"Left", "Right", "Forward", "Backward"}
+	type link struct {
+		prev planKey
+		act  int
+		cost float32
+	}
+	links := map[planKey]link{skey: {skey, -1, 0}}
+	done := map[planKey]bool{}
+	pq := &planQueue{{st: start, cost: 0, est: heur(start)}}
+	maxn := int(ev.Params["PlanMaxStates"])
+	for pq.Len() > 0 && len(done) < maxn {
+		nd := heap.Pop(pq).(*planNode)
+		nk := nd.st.Key()
+		if done[nk] {
+			continue
+		}
+		done[nk] = true
+		if goal(nd.st) {
+			acts := []int{}
+			sts := []planKey{}
+			costs := []float32{}
+			for k := nk; k != skey; {
+				lk := links[k]
+				acts = append([]int{lk.act}, acts...)
+				sts = append([]planKey{lk.prev}, sts...)
+				costs = append([]float32{nd.cost - links[lk.prev].cost}, costs...)
+				k = lk.prev
+			}
+			pc.States = append(sts, nk)
+			pc.Costs = append(costs, 0)
+			pc.Acts = acts
+			return acts, nd.cost
+		}
+		for _, as := range mvacts {
+			ns, ok := ev.PlanNext(nd.st, as)
+			if !ok {
+				continue
+			}
+			k := ns.Key()
+			if done[k] {
+				continue
+			}
+			cost := nd.cost + 1
+			if as == "Left" || as == "Right" {
+				cost = nd.cost + tc
+			}
+			if lk, has := links[k]; has && lk.cost <= cost {
+				continue
+			}
+			links[k] = link{nk, ev.ActMap[as], cost}
+			heap.Push(pq, &planNode{st: ns, cost: cost, est: cost + heur(ns)})
+		}
+	}
+	return nil, -1
+}
+
+// OracleTarget returns the drive of the current agent with the highest
+// urgency that has a consumable material, or nil if none
+func (ev *FWorld) OracleTarget() *Drive {
+	var trg *Drive
+	maxu := float32(-1)
+	for _, dr := range ev.Drives {
+		if dr.Mat == "" {
+			continue
+		}
+		u := ev.Urgency(dr)
+		if u > maxu {
+			trg = dr
+			maxu = u
+		}
+	}
+	return trg
+}
+
+// OracleAct returns the next action on the optimal path to the nearest
+// instance of the material for the most urgent drive (OracleTarget),
+// consuming it when it is in front.  If the material for another drive
+// is in front, it is consumed as well.  If there is no path, it falls
+// back on the heuristic ActGenHeur.
+func (ev *FWorld) OracleAct() int {
+	front := ev.DriveForMat(ev.MatName(ev.MatAt(ev.ProxPos[0])))
+	if front != nil {
+		return ev.ActMap[front.Act]
+	}
+	trg := ev.OracleTarget()
+	if trg == nil {
+		return ev.ActGenHeur()
+	}
+	acts, _ := ev.PlanTo(trg.Mat)
+	switch {
+	case acts == nil:
+		return ev.ActGenHeur()
+	case len(acts) == 0:
+		return ev.ActMap[trg.Act]
+	}
+	ev.ActGenTrace("oracle to: "+trg.Mat, acts[0])
+	return acts[0]
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"container/heap"
+	"testing"
+
+	"github.com/emer/emergent/evec"
+)
+
+// refPlanCost returns the minimum cost of movement actions from the
+// current agent state to facing given material, by uniform cost search
+// over PlanNext without any heuristic, or -1 if not found -- for checking PlanTo
+func refPlanCost(ev *FWorld, mat string) float32 {
+	mi := ev.MatMap[mat]
+	tc := ev.Params["PlanTurnCost"]
+	start := PlanState{ev.PosF, ev.PosI, ev.Angle % 360}
+	done := map[planKey]bool{}
+	pq := &planQueue{{st: start}}
+	for pq.Len() > 0 {
+		nd := heap.Pop(pq).(*planNode)
+		if done[nd.st.Key()] {
+			continue
+		}
+		done[nd.st.Key()] = true
+		if _, fp := NextVecPoint(nd.st.PosF, AngVec(nd.st.Angle)); ev.InWorld(fp) && ev.GetWorld(fp) == mi {
+			return nd.cost
+		}
+		for _, as := range []string{"Left", "Right", "Forward", "Backward"} {
+			ns, ok := ev.PlanNext(nd.st, as)
+			if !ok || done[ns.Key()] {
+				continue
+			}
+			cost := nd.cost + 1
+			if as == "Left" || as == "Right" {
+				cost = nd.cost + tc
+			}
+			heap.Push(pq, &planNode{st: ns, cost: cost, est: cost})
+		}
+	}
+	return -1
+}
+
+// checkPlan checks that PlanTo finds a plan to given material with the
+// optimal cost as computed by refPlanCost, and that taking its actions ends
+// up facing the material, returning the number of actions
+func checkPlan(t *testing.T, ev *FWorld, mat string) int {
+	acts, c := ev.PlanTo(mat)
+	if rc := refPlanCost(ev, mat); acts == nil || c != rc {
+		t.Fatalf("plan to: %s: %d actions cost: %g != optimal cost: %g", mat, len(acts), c, rc)
+	}
+	for _, a := range acts {
+		ev.Action(ev.Acts[a], nil)
+		ev.Step()
+	}
+	if _, fp := NextVecPoint(ev.PosF, AngVec(ev.Angle)); ev.GetWorld(fp) != ev.MatMap[mat] {
+		t.Errorf("agent at: %v angle: %d not facing: %s after the plan", ev.PosI, ev.Angle, mat)
+	}
+	return len(acts)
+}
+
+func TestPlanOpen(t *testing.T) {
+	ev := testEmptyWorld(t)
+	ev.InitAgent(evec.Vec2i{50, 50})
+	ev.SetWorld(evec.Vec2i{55, 50}, ev.MatMap["Food"])
+	if d := ev.DistField("Food").Value([]int{50, 50}); d != 5 {
+		t.Errorf("food distance: %g != 5", d)
+	}
+	if n := checkPlan(t, ev, "Food"); n != 4 { // straight ahead
+		t.Errorf("plan to food straight ahead: %d actions != 4", n)
+	}
+
+	ev.InitAgent(evec.Vec2i{50, 50})
+	ev.SetWorld(evec.Vec2i{55, 50}, 0)
+	ev.SetWorld(evec.Vec2i{50, 55}, ev.MatMap["Water"])
+	nturn := 90 / ev.AngInc
+	if n := checkPlan(t, ev, "Water"); n != nturn+4 { // turn left, then straight ahead
+		t.Errorf("plan to water to the left: %d actions != %d", n, nturn+4)
+	}
+
+	ev.InitAgent(evec.Vec2i{50, 50})
+	ev.Params["PlanTurnCost"] = 2 // not cached: fewer turns with diagonal moves
+	if n := checkPlan(t, ev, "Water"); n <= nturn+4 {
+		t.Errorf("plan to water with higher turn cost: %d actions not more than: %d", n, nturn+4)
+	}
+}
+
+func TestPlanWall(t *testing.T) {
+	ev := testEmptyWorld(t)
+	ev.InitAgent(evec.Vec2i{50, 50})
+	ev.WorldLineVert(evec.Vec2i{52, 48}, evec.Vec2i{52, 52}, ev.MatMap["Wall"])
+	ev.SetWorld(evec.Vec2i{54, 50}, ev.MatMap["Food"])
+	if d := ev.DistField("Food").Value([]int{50, 50}); d != 6 {
+		t.Errorf("food distance around the wall: %g != 6", d)
+	}
+	if n := checkPlan(t, ev, "Food"); n <= 3 {
+		t.Errorf("plan to food behind the wall: %d actions not more than straight ahead", n)
+	}
+}
+
+func TestPlanUnreachable(t *testing.T) {
+	ev := testEmptyWorld(t)
+	ev.InitAgent(evec.Vec2i{50, 50})
+	ev.WorldRect(evec.Vec2i{58, 58}, evec.Vec2i{62, 62}, ev.MatMap["Wall"])
+	ev.SetWorld(evec.Vec2i{60, 60}, ev.MatMap["Food"])
+	if d := ev.DistField("Food").Value([]int{50, 50}); d != -1 {
+		t.Errorf("food distance: %g != -1 for food inside walls", d)
+	}
+	if acts, cost := ev.PlanTo("Food"); acts != nil || cost != -1 {
+		t.Errorf("plan: %v cost: %g to food inside walls", acts, cost)
+	}
+	if acts, cost := ev.PlanTo("NoSuchMat"); acts != nil || cost != -1 {
+		t.Errorf("plan: %v cost: %g to unknown material", acts, cost)
+	}
+}
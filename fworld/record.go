@@ -0,0 +1,446 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+
+	"github.com/emer/emergent/env"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/gi"
+)
+
+// Snapshot is a full copy of the dynamic state of an FWorld,
+// sufficient to restore it exactly to the same point in time.
+type Snapshot struct {
+
+	// random seed that the env Rand was seeded with at the point of the snapshot
+	Seed int64 `desc:"random seed that the env Rand was seeded with at the point of the snapshot"`
+
+	// number of values drawn from the env Rand since it was seeded, i.e., the position in the random sequence
+	Draws int64 `desc:"number of values drawn from the env Rand since it was seeded, i.e., the position in the random sequence"`
+
+	// copy of the 2D grid world
+	World *etensor.Int `desc:"copy of the 2D grid world"`
+
+	// copies of the state of all the agents
+	Agents []*Agent `desc:"copies of the state of all the agents"`
+
+	// index of the current agent
+	CurAgent int `desc:"index of the current agent"`
+
+	// diffused odor fields
+	OdorFields map[string]*etensor.Float32 `desc:"diffused odor fields"`
+
+	// state of the moving entities
+	Entities []Entity `desc:"state of the moving entities"`
+
+	// state of the landmarks
+	Landmarks []Landmark `desc:"state of the landmarks"`
+
+	// rotation of the landmarks
+	LandmarkRot float32 `desc:"rotation of the landmarks"`
+
+	// pending refresh events
+	RefreshEvents map[int]*WEvent `desc:"pending refresh events"`
+
+	// all events
+	AllEvents map[int]*WEvent `desc:"all events"`
+
+	// counters, in order: Run, Epoch, Trial, Tick, Event, Scene, Episode
+	Ctrs []env.Ctr `desc:"counters, in order: Run, Epoch, Trial, Tick, Event, Scene, Episode"`
+
+	// state of the current episode
+	Ep EpisodeState `desc:"state of the current episode"`
+}
+
+// RandSrc is a rand.Source that counts the number of values drawn
+// since it was seeded, so that the position in the random sequence
+// can be saved in a Snapshot and restored exactly.
+type RandSrc struct {
+
+	// underlying source
+	Src rand.Source `desc:"underlying source"`
+
+	// number of values drawn since seeding
+	Draws int64 `desc:"number of values drawn since seeding"`
+}
+
+// NewRandSrc returns a new RandSrc seeded with given seed
+func NewRandSrc(seed int64) *RandSrc {
+	return &RandSrc{Src: rand.NewSource(seed)}
+}
+
+func (rs *RandSrc) Int63() int64 {
+	rs.Draws++
+	return rs.Src.Int63()
+}
+
+func (rs *RandSrc) Seed(seed int64) {
+	rs.Src.Seed(seed)
+	rs.Draws = 0
+}
+
+// Skip draws and discards n values, advancing to that position in the sequence
+func (rs *RandSrc) Skip(n int64) {
+	for i := int64(0); i < n; i++ {
+		rs.Int63()
+	}
+}
+
+// SeedRand seeds the env Rand with given seed, which is recorded in RndSeed,
+// using a RandSrc that counts the draws made from it
+func (ev *FWorld) SeedRand(seed int64) {
+	ev.RndSeed = seed
+	ev.RandSrc = NewRandSrc(seed)
+	ev.Rand.Rand = rand.New(ev.RandSrc)
+}
+
+// ActRec records one action taken by an agent
+type ActRec struct {
+
+	// Tick.Cur value when the action was taken
+	Tick int `desc:"Tick.Cur value when the action was taken"`
+
+	// ID of the agent that took the action
+	Agent string `desc:"ID of the agent that took the action"`
+
+	// number of the ActionAgents call that the action was taken in, together with the other agents, counting from 1 -- 0 for a single Action call
+	Group int `desc:"number of the ActionAgents call that the action was taken in, together with the other agents, counting from 1 -- 0 for a single Action call"`
+
+	// name of the action
+	Act string `desc:"name of the action"`
+
+	// for the Move action: normalized linear and angular velocity
+	Vals []float32 `desc:"for the Move action: normalized linear and angular velocity"`
+}
+
+// EpisodeRec is a record of an episode of behavior in the FWorld:
+// the starting snapshot (including random seed) and every action taken
+// since then.  Replaying the actions from the starting snapshot
+// exactly reproduces the same sequence of states.
+type EpisodeRec struct {
+
+	// [view: -] snapshot of the world state at the start of recording
+	Start *Snapshot `view:"-" desc:"snapshot of the world state at the start of recording"`
+
+	// sequence of actions taken -- actions with the same Group were taken together by multiple agents through ActionAgents, and a Step followed the last action with a given Tick
+	Acts []ActRec `desc:"sequence of actions taken -- actions with the same Group were taken together by multiple agents through ActionAgents, and a Step followed the last action with a given Tick"`
+
+	// number of ActionAgents calls recorded so far, used to number the action Groups
+	NGroups int `desc:"number of ActionAgents calls recorded so far, used to number the action Groups"`
+}
+
+// CopyCtrs returns a copy of all the counters, in standard order
+func (ev *FWorld) CopyCtrs() []env.Ctr {
+	return []env.Ctr{ev.Run, ev.Epoch, ev.Trial, ev.Tick, ev.Event, ev.Scene, ev.Episode}
+}
+
+// SetCtrs sets all the counters from a copy made by CopyCtrs
+func (ev *FWorld) SetCtrs(ctrs []env.Ctr) {
+	ev.Run, ev.Epoch, ev.Trial, ev.Tick = ctrs[0], ctrs[1], ctrs[2], ctrs[3]
+	ev.Event, ev.Scene, ev.Episode = ctrs[4], ctrs[5], ctrs[6]
+}
+
+// CopyStateMap returns a deep copy of given state map
+func CopyStateMap(sm map[string]*etensor.Float32) map[string]*etensor.Float32 {
+	cp := make(map[string]*etensor.Float32, len(sm))
+	for k, st := range sm {
+		cp[k] = st.Clone().(*etensor.Float32)
+	}
+	return cp
+}
+
+// SetStateMap copies the tensors in src into the existing map, adding any missing
+func SetStateMap(sm map[string]*etensor.Float32, src map[string]*etensor.Float32) {
+	for k, st := range src {
+		cs, ok := sm[k]
+		if !ok {
+			sm[k] = st.Clone().(*etensor.Float32)
+		} else {
+			cs.CopyFrom(st)
+		}
+	}
+}
+
+// CopyEvents returns a deep copy of given event map
+func CopyEvents(evs map[int]*WEvent) map[int]*WEvent {
+	cp := make(map[int]*WEvent, len(evs))
+	for t, wev := range evs {
+		we := *wev
+		cp[t] = &we
+	}
+	return cp
+}
+
+// Snapshot returns a full copy of the current dynamic state
+func (ev *FWorld) Snapshot() *Snapshot {
+	sn := &Snapshot{}
+	sn.Seed = ev.RndSeed
+	sn.Draws = ev.RandSrc.Draws
+	sn.World = ev.World.Clone().(*etensor.Int)
+	sn.Agents = make([]*Agent, len(ev.Agents))
+	for i, ag := range ev.Agents {
+		sn.Agents[i] = ag.Clone()
+	}
+	sn.CurAgent = ev.CurAgent
+	sn.OdorFields = CopyStateMap(ev.OdorFields)
+	sn.Entities = make([]Entity, len(ev.Entities))
+	for i, en := range ev.Entities {
+		sn.Entities[i] = *en
+	}
+	sn.Landmarks = make([]Landmark, len(ev.Landmarks))
+	for i, lm := range ev.Landmarks {
+		sn.Landmarks[i] = *lm
+	}
+	sn.LandmarkRot = ev.LandmarkRot
+	sn.RefreshEvents = CopyEvents(ev.RefreshEvents)
+	sn.AllEvents = CopyEvents(ev.AllEvents)
+	sn.Ctrs = ev.CopyCtrs()
+	sn.Ep = ev.Ep
+	return sn
+}
+
+// Restore restores the dynamic state from given snapshot,
+// and re-seeds the random number generator from the snapshot seed,
+// advancing it to the same position in the random sequence.
+// The world must already be configured with the same parameters.
+func (ev *FWorld) Restore(sn *Snapshot) {
+	ev.SeedRand(sn.Seed)
+	ev.RandSrc.Skip(sn.Draws)
+	ev.World.CopyFrom(sn.World)
+	for i, ag := range sn.Agents {
+		ev.Agents[i].CopyFrom(ag)
+	}
+	ev.SetAgentIdx(sn.CurAgent)
+	SetStateMap(ev.OdorFields, sn.OdorFields)
+	for i := range sn.Entities {
+		*ev.Entities[i] = sn.Entities[i]
+	}
+	for i := range sn.Landmarks {
+		*ev.Landmarks[i] = sn.Landmarks[i]
+	}
+	ev.LandmarkRot = sn.LandmarkRot
+	ev.RefreshEvents = CopyEvents(sn.RefreshEvents)
+	ev.AllEvents = CopyEvents(sn.AllEvents)
+	ev.SetCtrs(sn.Ctrs)
+	ev.Ep = sn.Ep
+	ev.UpdateLight()
+}
+
+////////////////////////////////////////////////////////////////////
+// Record / Replay
+
+// StartRecord starts recording a new episode, re-seeding the random
+// number generator with given seed so that any stochastic world
+// dynamics are reproducible.  Every subsequent Action call is recorded
+// in Rec, until StopRecord is called.
+func (ev *FWorld) StartRecord(seed int64) {
+	ev.SeedRand(seed)
+	ev.Rec = &EpisodeRec{Start: ev.Snapshot()}
+	ev.Recording = true
+}
+
+// StopRecord stops recording actions -- Rec retains the record
+func (ev *FWorld) StopRecord() {
+	ev.Recording = false
+}
+
+// RecordAct records given action in the current episode record, if Recording,
+// as part of given action group (0 for a single Action call)
+func (ev *FWorld) RecordAct(action string, group int) {
+	if !ev.Recording || ev.Rec == nil {
+		return
+	}
+	ar := ActRec{Tick: ev.Tick.Cur, Agent: ev.ID, Group: group, Act: action}
+	if action == "Move" {
+		ar.Vals = []float32{ev.MoveLin, ev.MoveAng}
+	}
+	ev.Rec.Acts = append(ev.Rec.Acts, ar)
+}
+
+// NewActGroup returns the number of a new group of actions taken together
+// through ActionAgents, if Recording -- otherwise 0
+func (ev *FWorld) NewActGroup() int {
+	if !ev.Recording || ev.Rec == nil {
+		return 0
+	}
+	ev.Rec.NGroups++
+	return ev.Rec.NGroups
+}
+
+// SaveRec saves the current episode record to a json file
+func (ev *FWorld) SaveRec(filename gi.FileName) error {
+	if ev.Rec == nil {
+		return fmt.Errorf("FWorld: %v no episode record to save -- need to StartRecord", ev.Nm)
+	}
+	jenc, err := json.Marshal(ev.Rec)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+	return ioutil.WriteFile(string(filename), jenc, 0644)
+}
+
+// OpenRec opens an episode record from a json file into Rec
+func (ev *FWorld) OpenRec(filename gi.FileName) error {
+	b, err := ioutil.ReadFile(string(filename))
+	if err != nil {
+		fmt.Println("Error opening file:", err)
+		return err
+	}
+	rec := &EpisodeRec{}
+	err = json.Unmarshal(b, rec)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+	ev.Rec = rec
+	return nil
+}
+
+// Replay restores the starting snapshot of the given episode record
+// and re-executes each recorded action, exactly reproducing the recorded
+// sequence of CurStates.  Actions recorded in the same Group by multiple
+// agents are replayed together through ActionAgents, and a Step is taken
+// after the last action recorded on each Tick.
+// If fun is non-nil it is called after each Step, with the index of the
+// last action before the Step in the record.
+// Recording is turned off during replay.  Returns an error if the
+// Tick of a replayed action does not match the record, which indicates
+// that the world configuration differs from when it was recorded.
+func (ev *FWorld) Replay(rec *EpisodeRec, fun func(idx int)) error {
+	ev.Recording = false
+	ev.Restore(rec.Start)
+	na := len(rec.Acts)
+	for i := 0; i < na; {
+		ar := rec.Acts[i]
+		if ev.Tick.Cur != ar.Tick {
+			return fmt.Errorf("FWorld: %v replay out of sync at action: %d, tick: %d != recorded: %d", ev.Nm, i, ev.Tick.Cur, ar.Tick)
+		}
+		n := 1
+		if ar.Group > 0 {
+			for i+n < na && rec.Acts[i+n].Group == ar.Group {
+				n++
+			}
+			acts := make(map[string]string, n)
+			for _, gr := range rec.Acts[i : i+n] {
+				acts[gr.Agent] = gr.Act
+				if ai, ok := ev.AgentMap[gr.Agent]; ok {
+					ev.Agents[ai].SetMoveVals(gr.Vals)
+				}
+			}
+			ev.ActionAgents(acts)
+		} else {
+			if ar.Agent != "" {
+				if err := ev.SetAgent(ar.Agent); err != nil {
+					return err
+				}
+			}
+			ev.SetMoveVals(ar.Vals)
+			ev.Action(ar.Act, nil)
+		}
+		i += n
+		if i < na && rec.Acts[i].Tick == ar.Tick {
+			continue
+		}
+		ev.Step()
+		if fun != nil {
+			fun(i - 1)
+		}
+	}
+	return nil
+}
+
+// SetMoveVals sets the Move action values from recorded vals, if present
+func (ag *Agent) SetMoveVals(vals []float32) {
+	if len(vals) < 2 {
+		return
+	}
+	ag.MoveLin = vals[0]
+	ag.MoveAng = vals[1]
+}
+
+// ReplayTraj replays the given episode record, recording the trajectory
+// into given table, which is configured by ConfigTrajTable, one row per tick.
+func (ev *FWorld) ReplayTraj(rec *EpisodeRec, dt *etable.Table) error {
+	ev.ConfigTrajTable(dt)
+	dt.SetNumRows(len(rec.Acts))
+	row := 0
+	err := ev.Replay(rec, func(idx int) {
+		ev.TrajRow(dt, row)
+		row++
+	})
+	dt.SetNumRows(row)
+	return err
+}
+
+// ConfigTrajTable configures a table for recording the trajectory of
+// the agent, one row per tick: position, angle, action, front material,
+// interoceptive states, and event flags.
+func (ev *FWorld) ConfigTrajTable(dt *etable.Table) {
+	dt.SetMetaData("name", "FWorldTraj")
+	dt.SetMetaData("desc", "trajectory of agent through FWorld, per tick")
+	dt.SetMetaData("read-only", "true")
+	dt.SetMetaData("precision", "4")
+
+	sch := etable.Schema{
+		{"Tick", etensor.INT64, nil, nil},
+		{"Event", etensor.INT64, nil, nil},
+		{"Scene", etensor.INT64, nil, nil},
+		{"PosX", etensor.FLOAT32, nil, nil},
+		{"PosY", etensor.FLOAT32, nil, nil},
+		{"Angle", etensor.INT64, nil, nil},
+		{"Action", etensor.STRING, nil, nil},
+		{"FrontMat", etensor.STRING, nil, nil},
+	}
+	for _, is := range ev.Inters {
+		sch = append(sch, etable.Column{is, etensor.FLOAT32, nil, nil})
+	}
+	sch = append(sch, etable.Column{"Bump", etensor.FLOAT32, nil, nil})
+	sch = append(sch, etable.Column{"Consume", etensor.FLOAT32, nil, nil})
+	dt.SetFromSchema(sch, 0)
+}
+
+// TrajRow records the current state into given row of a table
+// configured by ConfigTrajTable, adding rows as needed.
+func (ev *FWorld) TrajRow(dt *etable.Table, row int) {
+	if dt.Rows <= row {
+		dt.SetNumRows(row + 1)
+	}
+	dt.SetCellFloat("Tick", row, float64(ev.Tick.Cur))
+	dt.SetCellFloat("Event", row, float64(ev.Event.Cur))
+	dt.SetCellFloat("Scene", row, float64(ev.Scene.Cur))
+	dt.SetCellFloat("PosX", row, float64(ev.PosF.X))
+	dt.SetCellFloat("PosY", row, float64(ev.PosF.Y))
+	dt.SetCellFloat("Angle", row, float64(ev.Angle))
+	dt.SetCellString("Action", row, ev.Acts[ev.Act])
+	dt.SetCellString("FrontMat", row, ev.MatName(ev.ProxMats[0]))
+	for _, is := range ev.Inters {
+		dt.SetCellFloat(is, row, float64(ev.InterStates[is]))
+	}
+	bump := 0.0
+	if ev.InterStates["BumpPain"] > 0 {
+		bump = 1
+	}
+	dt.SetCellFloat("Bump", row, bump)
+	cons := 0.0
+	if ev.Scene.Chg {
+		cons = 1
+	}
+	dt.SetCellFloat("Consume", row, cons)
+}
+
+// MatName returns the name of given material index, or "" if out of range
+func (ev *FWorld) MatName(mat int) string {
+	if mat < 0 || mat >= len(ev.Mats) {
+		return ""
+	}
+	return ev.Mats[mat]
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/emer/etable/etensor"
+)
+
+// testDir changes to a temp dir with a copy of pats.json for the test,
+// so that any files written are not in the source dir
+func testDir(t *testing.T) string {
+	pats, err := ioutil.ReadFile("pats.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, _ := os.Getwd()
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "pats.json"), pats, 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chdir(dir)
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+// testWorld configures and inits a world in a temp dir, so that the
+// world.tsv and pats.json files are not written into the source dir,
+// with depth noise so that the states depend on the random sequence
+func testWorld(t *testing.T) *FWorld {
+	testDir(t)
+	ev := &FWorld{}
+	ev.Config(1000)
+	ev.Noise.DepthSD = 0.1
+	ev.Init(0)
+	return ev
+}
+
+// runSteps takes n steps with ActGen actions, returning the CurStates after each
+func runSteps(ev *FWorld, n int) []map[string]*etensor.Float32 {
+	sts := make([]map[string]*etensor.Float32, n)
+	for i := 0; i < n; i++ {
+		ev.Action(ev.Acts[ev.ActGen()], nil)
+		ev.Step()
+		sts[i] = CopyStateMap(ev.CurStates)
+	}
+	return sts
+}
+
+// checkStates checks that the CurStates after each step of a Replay
+// are the same as the given recorded ones
+func checkStates(t *testing.T, ev *FWorld, rec *EpisodeRec, sts []map[string]*etensor.Float32) {
+	n := 0
+	err := ev.Replay(rec, func(idx int) {
+		if n >= len(sts) {
+			t.Fatalf("replayed more steps than recorded: %d at action: %d", len(sts), idx)
+		}
+		for k, st := range sts[n] {
+			cs := ev.CurStates[k]
+			for i, v := range st.Values {
+				if cs.Values[i] != v {
+					t.Fatalf("replay step: %d state: %s differs at: %d: %g != recorded: %g", n, k, i, cs.Values[i], v)
+				}
+			}
+		}
+		n++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(sts) {
+		t.Errorf("replayed: %d steps != recorded: %d", n, len(sts))
+	}
+}
+
+func TestReplay(t *testing.T) {
+	ev := testWorld(t)
+	runSteps(ev, 20)
+	ev.StartRecord(42)
+	sts := runSteps(ev, 100)
+	ev.StopRecord()
+	checkStates(t, ev, ev.Rec, sts)
+}
+
+func TestReplayMidRun(t *testing.T) {
+	ev := testWorld(t)
+	ev.StartRecord(42)
+	runSteps(ev, 50)
+	nact := len(ev.Rec.Acts)
+	mid := &EpisodeRec{Start: ev.Snapshot()}
+	sts := runSteps(ev, 100)
+	ev.StopRecord()
+	mid.Acts = ev.Rec.Acts[nact:]
+	checkStates(t, ev, mid, sts)
+}
+
+// TestReplayGroups checks that multiple Action calls by one agent before
+// a Step, and ActionAgents groups, are replayed as they were taken
+func TestReplayGroups(t *testing.T) {
+	testDir(t)
+	ev := &FWorld{}
+	ev.ConfigDefaults(1000)
+	ev.AddAgent("Agent1")
+	if err := ev.ConfigPats(); err != nil {
+		t.Fatal(err)
+	}
+	ev.ConfigImpl()
+	ev.Noise.DepthSD = 0.1
+	ev.ShuffleAgents = true
+	ev.Init(0)
+	ev.StartRecord(42)
+	var sts []map[string]*etensor.Float32
+	for i := 0; i < 30; i++ {
+		switch i % 3 {
+		case 0:
+			ev.Action("Left", nil)
+			ev.Action("Forward", nil)
+		case 1:
+			ev.ActionAgents(ev.ActGenAgents())
+		case 2:
+			ev.ActionAgents(ev.ActGenAgents())
+			ev.ActionAgents(map[string]string{"Agent0": "Forward", "Agent1": "Forward"})
+		}
+		ev.Step()
+		sts = append(sts, CopyStateMap(ev.CurStates))
+	}
+	ev.StopRecord()
+	if ev.Rec.NGroups != 30 {
+		t.Errorf("recorded: %d ActionAgents groups != 30", ev.Rec.NGroups)
+	}
+	checkStates(t, ev, ev.Rec, sts)
+}
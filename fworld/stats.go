@@ -0,0 +1,270 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/gi/colormap"
+	"github.com/goki/gi/gi"
+)
+
+// TrajStats accumulates statistics over a trajectory of behavior of
+// all the agents in the FWorld: where they spent their time, what they
+// consumed, how often they bumped into things, and which actions they took,
+// along with the per-tick trajectory of the first agent.
+// Call Record after the actions on each tick, before Step.
+type TrajStats struct {
+
+	// number of ticks recorded
+	Steps int `desc:"number of ticks recorded"`
+
+	// number of episodes that terminated
+	Episodes int `desc:"number of episodes that terminated"`
+
+	// number of deaths of any agent from Lethal drives
+	Deaths int `desc:"number of deaths of any agent from Lethal drives"`
+
+	// number of ticks on which an agent felt BumpPain, from barriers or entities
+	Bumps int `desc:"number of ticks on which an agent felt BumpPain, from barriers or entities"`
+
+	// number of items consumed, keyed by material name
+	Consumed map[string]int `desc:"number of items consumed, keyed by material name"`
+
+	// number of times each action was taken, in the order of Acts
+	ActCounts []int `desc:"number of times each action was taken, in the order of Acts"`
+
+	// [view: no-inline] number of ticks any agent spent in each cell of the world, same shape as World
+	Occupancy *etensor.Float32 `view:"no-inline" desc:"number of ticks any agent spent in each cell of the world, same shape as World"`
+
+	// [view: no-inline] per-tick trajectory of the first agent, including interoceptive states -- see ConfigTrajTable
+	Traj *etable.Table `view:"no-inline" desc:"per-tick trajectory of the first agent, including interoceptive states -- see ConfigTrajTable"`
+}
+
+// Init initializes the stats for given world
+func (ts *TrajStats) Init(ev *FWorld) {
+	ts.Steps = 0
+	ts.Episodes = 0
+	ts.Deaths = 0
+	ts.Bumps = 0
+	ts.Consumed = make(map[string]int)
+	ts.ActCounts = make([]int, len(ev.Acts))
+	ts.Occupancy = &etensor.Float32{}
+	ts.Occupancy.SetShape([]int{ev.Size.Y, ev.Size.X}, nil, []string{"Y", "X"})
+	ts.Traj = etable.NewTable("FWorldTraj")
+	ev.ConfigTrajTable(ts.Traj)
+}
+
+// Record records the results of the last actions of all agents
+func (ts *TrajStats) Record(ev *FWorld) {
+	ev.AgentsDo(func(ag *Agent) {
+		if ev.InWorld(ag.PosI) {
+			ts.Occupancy.Values[ag.PosI.Y*ev.Size.X+ag.PosI.X]++
+		}
+		if ag.Act >= 0 && ag.Act < len(ts.ActCounts) {
+			ts.ActCounts[ag.Act]++
+		}
+		if ag.Consumed != "" {
+			ts.Consumed[ag.Consumed]++
+		}
+		if ag.InterStates["BumpPain"] > 0 {
+			ts.Bumps++
+		}
+		if ag.Dead {
+			ts.Deaths++
+		}
+		if ev.CurAgent == 0 {
+			ev.TrajRow(ts.Traj, ts.Steps)
+		}
+	})
+	if ev.Ep.Done() {
+		ts.Episodes++
+	}
+	ts.Steps++
+}
+
+// Rate returns given count per 1000 ticks
+func (ts *TrajStats) Rate(n int) float64 {
+	if ts.Steps == 0 {
+		return 0
+	}
+	return 1000 * float64(n) / float64(ts.Steps)
+}
+
+// ConfigSummaryTable configures a table for the summary stats of a set
+// of runs, one row per run: the values of given parameters, followed by
+// the stats, with consumption of each drive material and bumps as counts
+// and rates per 1000 ticks.
+func (ts *TrajStats) ConfigSummaryTable(ev *FWorld, dt *etable.Table, params []string) {
+	dt.SetMetaData("name", "FWorldSummary")
+	dt.SetMetaData("desc", "summary stats of FWorld runs, one row per run")
+	dt.SetMetaData("read-only", "true")
+	dt.SetMetaData("precision", "4")
+
+	sch := etable.Schema{}
+	for _, pn := range params {
+		sch = append(sch, etable.Column{pn, etensor.FLOAT32, nil, nil})
+	}
+	sch = append(sch, etable.Schema{
+		{"Steps", etensor.INT64, nil, nil},
+		{"Episodes", etensor.INT64, nil, nil},
+		{"Deaths", etensor.INT64, nil, nil},
+		{"Bumps", etensor.INT64, nil, nil},
+		{"BumpRate", etensor.FLOAT32, nil, nil},
+	}...)
+	for _, dr := range ev.Drives {
+		if dr.Mat == "" {
+			continue
+		}
+		sch = append(sch, etable.Column{dr.Mat, etensor.INT64, nil, nil})
+		sch = append(sch, etable.Column{dr.Mat + "Rate", etensor.FLOAT32, nil, nil})
+	}
+	dt.SetFromSchema(sch, 0)
+}
+
+// SummaryRow records the summary stats into given row of a table
+// configured by ConfigSummaryTable, adding rows as needed.
+func (ts *TrajStats) SummaryRow(ev *FWorld, dt *etable.Table, row int, params []string) {
+	if dt.Rows <= row {
+		dt.SetNumRows(row + 1)
+	}
+	for _, pn := range params {
+		dt.SetCellFloat(pn, row, float64(ev.Params[pn]))
+	}
+	dt.SetCellFloat("Steps", row, float64(ts.Steps))
+	dt.SetCellFloat("Episodes", row, float64(ts.Episodes))
+	dt.SetCellFloat("Deaths", row, float64(ts.Deaths))
+	dt.SetCellFloat("Bumps", row, float64(ts.Bumps))
+	dt.SetCellFloat("BumpRate", row, ts.Rate(ts.Bumps))
+	for _, dr := range ev.Drives {
+		if dr.Mat == "" {
+			continue
+		}
+		n := ts.Consumed[dr.Mat]
+		dt.SetCellFloat(dr.Mat, row, float64(n))
+		dt.SetCellFloat(dr.Mat+"Rate", row, ts.Rate(n))
+	}
+}
+
+// ActHistTable returns a table with the action histogram:
+// the count and fraction of ticks on which each action was taken
+func (ts *TrajStats) ActHistTable(ev *FWorld) *etable.Table {
+	dt := etable.NewTable("FWorldActHist")
+	dt.SetMetaData("precision", "4")
+	sch := etable.Schema{
+		{"Action", etensor.STRING, nil, nil},
+		{"Count", etensor.INT64, nil, nil},
+		{"Frac", etensor.FLOAT32, nil, nil},
+	}
+	dt.SetFromSchema(sch, len(ev.Acts))
+	tot := 0
+	for _, n := range ts.ActCounts {
+		tot += n
+	}
+	for i, as := range ev.Acts {
+		n := ts.ActCounts[i]
+		dt.SetCellString("Action", i, as)
+		dt.SetCellFloat("Count", i, float64(n))
+		if tot > 0 {
+			dt.SetCellFloat("Frac", i, float64(n)/float64(tot))
+		}
+	}
+	return dt
+}
+
+// SaveOccupancy saves the occupancy counts as a tab-separated grid,
+// one line per row (Y) of the world
+func (ts *TrajStats) SaveOccupancy(filename gi.FileName) error {
+	fp, err := os.Create(string(filename))
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	bw := bufio.NewWriter(fp)
+	ny := ts.Occupancy.Dim(0)
+	nx := ts.Occupancy.Dim(1)
+	for y := 0; y < ny; y++ {
+		for x := 0; x < nx; x++ {
+			if x > 0 {
+				bw.WriteString("\t")
+			}
+			fmt.Fprintf(bw, "%g", ts.Occupancy.Values[y*nx+x])
+		}
+		bw.WriteString("\n")
+	}
+	return bw.Flush()
+}
+
+// SaveOccupancyPNG saves the occupancy as a heatmap image, normalized by the
+// maximum count, with barriers in white, and each cell drawn as scale x scale
+// pixels.  Y is flipped so that up in the image is up in the world views.
+func (ts *TrajStats) SaveOccupancyPNG(ev *FWorld, filename gi.FileName, scale int) error {
+	if scale < 1 {
+		scale = 1
+	}
+	cm := colormap.AvailMaps["Viridis"]
+	ny := ts.Occupancy.Dim(0)
+	nx := ts.Occupancy.Dim(1)
+	mx := float32(0)
+	for _, v := range ts.Occupancy.Values {
+		if v > mx {
+			mx = v
+		}
+	}
+	img := image.NewRGBA(image.Rect(0, 0, nx*scale, ny*scale))
+	for y := 0; y < ny; y++ {
+		for x := 0; x < nx; x++ {
+			v := ts.Occupancy.Values[y*nx+x]
+			clr := cm.Map(0)
+			switch {
+			case v == 0 && ev.IsBarrier(ev.World.Values[y*nx+x]):
+				clr.SetUInt8(255, 255, 255, 255)
+			case mx > 0:
+				clr = cm.Map(float64(v / mx))
+			}
+			iy := ny - 1 - y
+			for py := 0; py < scale; py++ {
+				for px := 0; px < scale; px++ {
+					img.Set(x*scale+px, iy*scale+py, clr)
+				}
+			}
+		}
+	}
+	fp, err := os.Create(string(filename))
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	return png.Encode(fp, img)
+}
+
+// Save saves all the stats to files starting with given prefix:
+// _occupancy.tsv, _occupancy.png (if png), _acts.tsv, and _traj.tsv (if traj)
+func (ts *TrajStats) Save(ev *FWorld, prefix string, pngs, traj bool) error {
+	if err := ts.SaveOccupancy(gi.FileName(prefix + "_occupancy.tsv")); err != nil {
+		return err
+	}
+	if pngs {
+		if err := ts.SaveOccupancyPNG(ev, gi.FileName(prefix+"_occupancy.png"), 4); err != nil {
+			return err
+		}
+	}
+	if err := ts.ActHistTable(ev).SaveCSV(gi.FileName(prefix+"_acts.tsv"), etable.Tab, etable.Headers); err != nil {
+		return err
+	}
+	if traj {
+		ts.Traj.SetNumRows(ts.Steps)
+		if err := ts.Traj.SaveCSV(gi.FileName(prefix+"_traj.tsv"), etable.Tab, etable.Headers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
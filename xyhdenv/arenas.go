@@ -0,0 +1,238 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/emer/emergent/evec"
+	"github.com/goki/ki/ints"
+	"github.com/goki/ki/kit"
+	"github.com/goki/mat32"
+)
+
+//go:generate stringer -type=Arenas
+
+// Arenas are the preset arena geometries generated by GenWorld, scaled to
+// the Size of the world.  Everything outside of the arena is filled with Wall.
+type Arenas int32
+
+const (
+	// ArenaRect is the entire world inside a wall around the edges
+	ArenaRect Arenas = iota
+
+	// ArenaSquare is the largest square that fits in the center of the world
+	ArenaSquare
+
+	// ArenaCircle is the largest circle that fits in the center of the world
+	ArenaCircle
+
+	// ArenaTMaze is a T-maze, with a stem of ArmWidth up the middle from the
+	// min Y edge, and a crossbar along the max Y edge.  The agent starts at
+	// the bottom of the stem.
+	ArenaTMaze
+
+	// ArenaPlusMaze is a plus-maze, with arms of ArmWidth along the middle
+	// of the world in X and Y, crossing in the center
+	ArenaPlusMaze
+
+	// ArenaTwoRoom is two rooms, divided by a wall along the middle in X,
+	// with a door of DoorWidth in the middle of the wall.  The agent starts
+	// in the center of the min X room.
+	ArenaTwoRoom
+
+	// ArenaBarriers is the entire world with the interior Barriers
+	ArenaBarriers
+
+	ArenasN
+)
+
+var KiT_Arenas = kit.Enums.AddEnum(ArenasN, kit.NotBitFlag, nil)
+
+func (ev Arenas) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *Arenas) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+
+// Barrier is a straight interior barrier, with endpoints in proportions of
+// the Size of the world (0..1), so it scales with the world
+type Barrier struct {
+
+	// starting point, in proportions of the Size of the world
+	St mat32.Vec2 `desc:"starting point, in proportions of the Size of the world"`
+
+	// ending point, in proportions of the Size of the world
+	Ed mat32.Vec2 `desc:"ending point, in proportions of the Size of the world"`
+}
+
+// ArenaParams are the parameters for the arena geometry, in proportions of
+// the Size of the world, so that each arena scales with the world
+type ArenaParams struct {
+
+	// width of the arms of the T-maze and plus-maze, as a proportion of the Size of the world
+	ArmWidth float32 `desc:"width of the arms of the T-maze and plus-maze, as a proportion of the Size of the world"`
+
+	// width of the door between the two rooms, as a proportion of the Size of the world
+	DoorWidth float32 `desc:"width of the door between the two rooms, as a proportion of the Size of the world"`
+
+	// interior barriers for the Barriers arena
+	Barriers []Barrier `desc:"interior barriers for the Barriers arena"`
+}
+
+// Defaults sets the default arena parameters
+func (ap *ArenaParams) Defaults() {
+	ap.ArmWidth = 0.2
+	ap.DoorWidth = 0.1
+	ap.Barriers = []Barrier{
+		{St: mat32.Vec2{0.25, 0.35}, Ed: mat32.Vec2{0.6, 0.35}},
+		{St: mat32.Vec2{0.7, 0.5}, Ed: mat32.Vec2{0.7, 0.85}},
+	}
+}
+
+// SetArena generates the world for given arena, and moves the agent to the
+// start of the arena if it is now in a barrier.  Can be called during a run,
+// as in remapping experiments.
+func (ev *XYHDEnv) SetArena(arena Arenas) {
+	ev.Arena = arena
+	ev.GenWorld()
+	ev.CheckArenaPos()
+	if ev.Traj.Visits != nil {
+		ev.UpdtFreeBins()
+	}
+}
+
+// SetArenaName sets the arena by name, with or without the Arena prefix,
+// e.g., "Circle" or "ArenaCircle"
+func (ev *XYHDEnv) SetArenaName(name string) error {
+	var arena Arenas
+	if err := arena.FromString(name); err != nil {
+		if err := arena.FromString("Arena" + name); err != nil {
+			return fmt.Errorf("XYHDEnv SetArenaName: arena named: %s not found", name)
+		}
+	}
+	ev.SetArena(arena)
+	return nil
+}
+
+// InsertBarrier draws a barrier line of Wall into the current world, with
+// endpoints in proportions of the Size of the world, and moves the agent to
+// the start of the arena if it is now in the barrier -- as in
+// barrier-insertion experiments.  SetArena removes inserted barriers.
+func (ev *XYHDEnv) InsertBarrier(st, ed mat32.Vec2) {
+	ev.ArenaLine(Barrier{St: st, Ed: ed}, ev.MatMap["Wall"])
+	ev.CheckArenaPos()
+	if ev.Traj.Visits != nil {
+		ev.UpdtFreeBins()
+	}
+}
+
+// CheckArenaPos moves the agent to the ArenaStart if it is in a barrier
+func (ev *XYHDEnv) CheckArenaPos() {
+	if !ev.IsBarrier(ev.GetWorld(ev.PosI)) {
+		return
+	}
+	ev.PosI = ev.ArenaStart()
+	ev.PosF = ev.PosI.ToVec2()
+	ev.ScanProx()
+}
+
+// ArenaStart returns the starting position of the agent in the current
+// arena: the center of the world, except as noted in Arenas, or the
+// nearest free cell to it
+func (ev *XYHDEnv) ArenaStart() evec.Vec2i {
+	st := ev.Size.DivScalar(2)
+	switch ev.Arena {
+	case ArenaTMaze:
+		st.Y = 1 + ev.ArenaProp(ev.ArenaParams.ArmWidth)/2
+	case ArenaTwoRoom:
+		st.X = ev.Size.X / 4
+	}
+	return ev.FreeNear(st)
+}
+
+// FreeNear returns the nearest cell to given point that is not a barrier,
+// searching in squares of increasing size around it
+func (ev *XYHDEnv) FreeNear(p evec.Vec2i) evec.Vec2i {
+	mx := ints.MaxInt(ev.Size.X, ev.Size.Y)
+	for r := 0; r < mx; r++ {
+		for y := p.Y - r; y <= p.Y+r; y++ {
+			for x := p.X - r; x <= p.X+r; x++ {
+				if ints.MaxInt(ints.AbsInt(x-p.X), ints.AbsInt(y-p.Y)) != r {
+					continue
+				}
+				c := evec.Vec2i{x, y}
+				if ev.InWorld(c) && !ev.IsBarrier(ev.GetWorld(c)) {
+					return c
+				}
+			}
+		}
+	}
+	return p
+}
+
+// ArenaProp returns given proportion of the smaller dimension of the
+// world, in cells, at least 1
+func (ev *XYHDEnv) ArenaProp(prop float32) int {
+	return ints.MaxInt(int(prop*float32(ints.MinInt(ev.Size.X, ev.Size.Y))), 1)
+}
+
+// ArenaLine draws given barrier in the world, with given mat
+func (ev *XYHDEnv) ArenaLine(br Barrier, mat int) {
+	sz := ev.Size.ToVec2().SubScalar(1)
+	st := evec.NewVec2iFmVec2Round(br.St.Mul(sz))
+	ed := evec.NewVec2iFmVec2Round(br.Ed.Mul(sz))
+	ev.WorldLine(st, ed, mat)
+}
+
+// WorldFillRect fills the rectangle in world with given mat, inclusive of
+// the start and end points
+func (ev *XYHDEnv) WorldFillRect(st, ed evec.Vec2i, mat int) {
+	for y := ints.MinInt(st.Y, ed.Y); y <= ints.MaxInt(st.Y, ed.Y); y++ {
+		ev.WorldLineHoriz(evec.Vec2i{st.X, y}, evec.Vec2i{ed.X, y}, mat)
+	}
+}
+
+// GenArena generates the current Arena into the world, which must
+// already be filled with wall
+func (ev *XYHDEnv) GenArena() {
+	ap := &ev.ArenaParams
+	wall := ev.MatMap["Wall"]
+	mx, my := ev.Size.X-2, ev.Size.Y-2 // max interior cells
+	ctr := ev.Size.DivScalar(2)
+	switch ev.Arena {
+	case ArenaRect:
+		ev.WorldFillRect(evec.Vec2i{1, 1}, evec.Vec2i{mx, my}, 0)
+	case ArenaSquare:
+		n := ints.MinInt(ev.Size.X, ev.Size.Y)
+		st := evec.Vec2i{(ev.Size.X - n) / 2, (ev.Size.Y - n) / 2}
+		ev.WorldFillRect(st.AddScalar(1), st.AddScalar(n-2), 0)
+	case ArenaCircle:
+		c := ev.Size.ToVec2().SubScalar(1).DivScalar(2)
+		r := 0.5*float32(ints.MinInt(ev.Size.X, ev.Size.Y)) - 1
+		for y := 1; y <= my; y++ {
+			for x := 1; x <= mx; x++ {
+				if c.DistTo(mat32.Vec2{float32(x), float32(y)}) <= r {
+					ev.SetWorld(evec.Vec2i{x, y}, 0)
+				}
+			}
+		}
+	case ArenaTMaze:
+		w := ev.ArenaProp(ap.ArmWidth)
+		ev.WorldFillRect(evec.Vec2i{ctr.X - w/2, 1}, evec.Vec2i{ctr.X - w/2 + w - 1, my}, 0)
+		ev.WorldFillRect(evec.Vec2i{1, my - w + 1}, evec.Vec2i{mx, my}, 0)
+	case ArenaPlusMaze:
+		w := ev.ArenaProp(ap.ArmWidth)
+		ev.WorldFillRect(evec.Vec2i{ctr.X - w/2, 1}, evec.Vec2i{ctr.X - w/2 + w - 1, my}, 0)
+		ev.WorldFillRect(evec.Vec2i{1, ctr.Y - w/2}, evec.Vec2i{mx, ctr.Y - w/2 + w - 1}, 0)
+	case ArenaTwoRoom:
+		d := ev.ArenaProp(ap.DoorWidth)
+		ev.WorldFillRect(evec.Vec2i{1, 1}, evec.Vec2i{mx, my}, 0)
+		ev.WorldLineVert(evec.Vec2i{ctr.X, 1}, evec.Vec2i{ctr.X, my}, wall)
+		ev.WorldLineVert(evec.Vec2i{ctr.X, ctr.Y - d/2}, evec.Vec2i{ctr.X, ctr.Y - d/2 + d - 1}, 0)
+	case ArenaBarriers:
+		ev.WorldFillRect(evec.Vec2i{1, 1}, evec.Vec2i{mx, my}, 0)
+		for _, br := range ap.Barriers {
+			ev.ArenaLine(br, wall)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/emer/emergent/evec"
+	"github.com/goki/mat32"
+)
+
+// freeCells returns the number of cells that are not barriers, and the
+// number of them reachable from given start by 4-connected moves
+func freeCells(ev *XYHDEnv, st evec.Vec2i) (nfree, nreach int) {
+	free := func(p evec.Vec2i) bool {
+		return ev.InWorld(p) && !ev.IsBarrier(ev.GetWorld(p))
+	}
+	for y := 0; y < ev.Size.Y; y++ {
+		for x := 0; x < ev.Size.X; x++ {
+			if free(evec.Vec2i{x, y}) {
+				nfree++
+			}
+		}
+	}
+	seen := map[evec.Vec2i]bool{st: true}
+	front := []evec.Vec2i{st}
+	for len(front) > 0 {
+		var next []evec.Vec2i
+		for _, p := range front {
+			for _, d := range []evec.Vec2i{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+				np := p.Add(d)
+				if !seen[np] && free(np) {
+					seen[np] = true
+					next = append(next, np)
+				}
+			}
+		}
+		front = next
+	}
+	return nfree, len(seen)
+}
+
+func TestArenas(t *testing.T) {
+	ev := testEnv(t, nil)
+	ctr := ev.Size.DivScalar(2)
+	for a := ArenaRect; a < ArenasN; a++ {
+		ev.SetArena(a)
+		st := ev.ArenaStart()
+		if ev.IsBarrier(ev.GetWorld(st)) || ev.IsBarrier(ev.GetWorld(ev.PosI)) {
+			t.Errorf("arena: %v start: %v or agent: %v in a barrier", a, st, ev.PosI)
+		}
+		nfree, nreach := freeCells(ev, st)
+		if nfree == 0 || nreach != nfree {
+			t.Errorf("arena: %v only: %d of: %d free cells reachable from the start", a, nreach, nfree)
+		}
+		for x := 0; x < ev.Size.X; x++ {
+			if !ev.IsBarrier(ev.GetWorld(evec.Vec2i{x, 0})) {
+				t.Errorf("arena: %v no wall at the edge: %d", a, x)
+				break
+			}
+		}
+		corner := ev.IsBarrier(ev.GetWorld(evec.Vec2i{1, 1}))
+		switch a {
+		case ArenaRect, ArenaSquare, ArenaTwoRoom, ArenaBarriers:
+			if corner {
+				t.Errorf("arena: %v corner is a barrier", a)
+			}
+		default:
+			if !corner {
+				t.Errorf("arena: %v corner is not a barrier", a)
+			}
+		}
+		switch a {
+		case ArenaTMaze:
+			if st.Y >= ctr.Y {
+				t.Errorf("T-maze start: %v not at the bottom of the stem", st)
+			}
+		case ArenaTwoRoom:
+			if st.X >= ctr.X || !ev.IsBarrier(ev.GetWorld(evec.Vec2i{ctr.X, 1})) || ev.IsBarrier(ev.GetWorld(ctr)) {
+				t.Errorf("two room start: %v or dividing wall with door wrong", st)
+			}
+		}
+	}
+	if err := ev.SetArenaName("Circle"); err != nil || ev.Arena != ArenaCircle {
+		t.Errorf("SetArenaName Circle: %v arena: %v", err, ev.Arena)
+	}
+	if err := ev.SetArenaName("ArenaPlusMaze"); err != nil || ev.Arena != ArenaPlusMaze {
+		t.Errorf("SetArenaName ArenaPlusMaze: %v arena: %v", err, ev.Arena)
+	}
+	if err := ev.SetArenaName("Hexagon"); err == nil {
+		t.Error("SetArenaName: no error for an unknown arena")
+	}
+}
+
+func TestInsertBarrier(t *testing.T) {
+	ev := testEnv(t, nil)
+	runSteps(ev, 50)
+	pi := ev.PosI
+	p := pi.ToVec2().Div(ev.Size.ToVec2().SubScalar(1))
+	ev.InsertBarrier(mat32.Vec2{0.1, p.Y}, mat32.Vec2{0.9, p.Y}) // right through the agent
+	if ev.IsBarrier(ev.GetWorld(ev.PosI)) {
+		t.Fatalf("agent: %v left in the inserted barrier", ev.PosI)
+	}
+	if !ev.IsBarrier(ev.GetWorld(pi)) {
+		t.Errorf("no barrier inserted at: %v", pi)
+	}
+	runSteps(ev, 50)
+	ev.SetArena(ev.Arena)
+	if nfree, nreach := freeCells(ev, ev.PosI); nreach != nfree || nfree != (ev.Size.X-2)*(ev.Size.Y-2) {
+		t.Errorf("barrier not removed by SetArena: %d of: %d free cells reachable", nreach, nfree)
+	}
+}
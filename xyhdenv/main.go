@@ -98,6 +98,11 @@ func (ss *Sim) Config() {
 		{"Position", etensor.FLOAT32, ss.World.CurStates["Position"].Shape.Shp, nil},
 		{"Action", etensor.FLOAT32, ss.World.CurStates["Action"].Shape.Shp, nil},
 	}
+	for _, nm := range []string{"Place", "Grid", "BVC", "Velocity", "Rotation", "Reward", "Cue"} { // optional states
+		if st, ok := ss.World.CurStates[nm]; ok {
+			sch = append(sch, etable.Column{nm, etensor.FLOAT32, st.Shape.Shp, nil})
+		}
+	}
 	ss.State = etable.NewTable("input")
 	ss.State.SetFromSchema(sch, 1)
 	ss.State.SetMetaData("TrialName:width", "50")
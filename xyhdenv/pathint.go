@@ -0,0 +1,293 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/emer/emergent/erand"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/mat32"
+)
+
+// PathInt configures the path-integration mode, in which the true Position
+// and Angle inputs (and their Prev versions) are withheld (all zeros) on a
+// dropout schedule, or degraded by noise when shown, and noisy Velocity
+// (speed moved) and Rotation (angle rotated) self-motion signals are added.
+// The ground truth is logged on each Step, along with a dead-reckoning
+// estimate integrated from the noisy self-motion signals, which is reset to
+// the truth whenever it is shown, as a baseline for integration error.
+type PathInt struct {
+
+	// use the path-integration mode -- call ConfigPathIntImpl after changing
+	On bool `desc:"use the path-integration mode -- call ConfigPathIntImpl after changing"`
+
+	// [viewif: On] apply the dropout schedule to the Position and PrevPosition inputs
+	DropPos bool `viewif:"On" desc:"apply the dropout schedule to the Position and PrevPosition inputs"`
+
+	// [viewif: On] apply the dropout schedule to the Angle and PrevAngle inputs
+	DropAngle bool `viewif:"On" desc:"apply the dropout schedule to the Angle and PrevAngle inputs"`
+
+	// [viewif: On] number of steps in each cycle of the schedule that the inputs are shown -- 0 = always withheld
+	ShowSteps int `viewif:"On" desc:"number of steps in each cycle of the schedule that the inputs are shown -- 0 = always withheld"`
+
+	// [viewif: On] number of steps in each cycle of the schedule that the inputs are withheld, after the ShowSteps -- 0 = never withheld by the schedule
+	HideSteps int `viewif:"On" desc:"number of steps in each cycle of the schedule that the inputs are withheld, after the ShowSteps -- 0 = never withheld by the schedule"`
+
+	// [viewif: On] probability of withholding the inputs on each step when they are shown by the schedule
+	DropP float32 `viewif:"On" desc:"probability of withholding the inputs on each step when they are shown by the schedule"`
+
+	// [viewif: On] standard deviation of the noise added to the Position inputs when shown, in world cells
+	PosNoise float32 `viewif:"On" desc:"standard deviation of the noise added to the Position inputs when shown, in world cells"`
+
+	// [viewif: On] standard deviation of the noise added to the Angle inputs when shown, in degrees
+	AngNoise float32 `viewif:"On" desc:"standard deviation of the noise added to the Angle inputs when shown, in degrees"`
+
+	// [viewif: On] standard deviation of the noise added to the Velocity signal, in world cells
+	VelNoise float32 `viewif:"On" desc:"standard deviation of the noise added to the Velocity signal, in world cells"`
+
+	// [viewif: On] standard deviation of the noise added to the Rotation signal, in degrees
+	RotNoise float32 `viewif:"On" desc:"standard deviation of the noise added to the Rotation signal, in degrees"`
+
+	// [viewif: On] maximum speed encoded in the Velocity signal, in world cells per step
+	MaxSpeed float32 `viewif:"On" desc:"maximum speed encoded in the Velocity signal, in world cells per step"`
+
+	// [viewif: On] seed for the random number generator for the noise and dropout, set in Init
+	RndSeed int64 `viewif:"On" desc:"seed for the random number generator for the noise and dropout, set in Init"`
+
+	// number of steps rendered in the current run
+	Steps int `inactive:"+" desc:"number of steps rendered in the current run"`
+
+	// whether the Position inputs are shown on the current step
+	PosShown bool `inactive:"+" desc:"whether the Position inputs are shown on the current step"`
+
+	// whether the Angle inputs are shown on the current step
+	AngShown bool `inactive:"+" desc:"whether the Angle inputs are shown on the current step"`
+
+	// noisy speed moved on the current step, in world cells
+	Speed float32 `inactive:"+" desc:"noisy speed moved on the current step, in world cells"`
+
+	// noisy angle rotated on the current step, in degrees
+	Rot float32 `inactive:"+" desc:"noisy angle rotated on the current step, in degrees"`
+
+	// dead-reckoning estimate of the position, from the noisy self-motion signals
+	EstPos mat32.Vec2 `inactive:"+" desc:"dead-reckoning estimate of the position, from the noisy self-motion signals"`
+
+	// dead-reckoning estimate of the angle, in degrees, from the noisy self-motion signals
+	EstAngle float32 `inactive:"+" desc:"dead-reckoning estimate of the angle, in degrees, from the noisy self-motion signals"`
+
+	// log of the ground truth and dead-reckoning estimate on each Step of the current run
+	Log []PathIntRec `view:"-" desc:"log of the ground truth and dead-reckoning estimate on each Step of the current run"`
+
+	// [view: -] random number generator for the noise and dropout
+	Rand erand.SysRand `view:"-" desc:"random number generator for the noise and dropout"`
+}
+
+// PathIntRec records the ground truth and the dead-reckoning estimate
+// for one step of path integration
+type PathIntRec struct {
+
+	// Tick counter value
+	Tick int `desc:"Tick counter value"`
+
+	// true position
+	Pos mat32.Vec2 `desc:"true position"`
+
+	// true angle, in degrees
+	Angle float32 `desc:"true angle, in degrees"`
+
+	// whether the Position inputs were shown
+	PosShown bool `desc:"whether the Position inputs were shown"`
+
+	// whether the Angle inputs were shown
+	AngShown bool `desc:"whether the Angle inputs were shown"`
+
+	// dead-reckoning estimate of the position
+	EstPos mat32.Vec2 `desc:"dead-reckoning estimate of the position"`
+
+	// dead-reckoning estimate of the angle, in degrees
+	EstAngle float32 `desc:"dead-reckoning estimate of the angle, in degrees"`
+
+	// distance between the estimated and true position
+	PosErr float32 `desc:"distance between the estimated and true position"`
+
+	// absolute difference between the estimated and true angle, in degrees (0..180)
+	AngErr float32 `desc:"absolute difference between the estimated and true angle, in degrees (0..180)"`
+}
+
+// Defaults sets default parameters, with the mode off
+func (pi *PathInt) Defaults() {
+	pi.DropPos = true
+	pi.DropAngle = true
+	pi.ShowSteps = 10
+	pi.HideSteps = 40
+	pi.PosNoise = 1
+	pi.AngNoise = 5
+	pi.VelNoise = 0.1
+	pi.RotNoise = 5
+	pi.MaxSpeed = 1.5
+	pi.RndSeed = 1
+}
+
+// Init initializes the state for a new run, starting the estimate at
+// given true position and angle
+func (pi *PathInt) Init(pos mat32.Vec2, ang float32) {
+	pi.Rand.NewRand(pi.RndSeed)
+	pi.Steps = 0
+	pi.PosShown = true
+	pi.AngShown = true
+	pi.Speed = 0
+	pi.Rot = 0
+	pi.EstPos = pos
+	pi.EstAngle = ang
+	pi.Log = nil
+}
+
+// Shown returns whether the inputs are shown on the current step,
+// according to the schedule and DropP
+func (pi *PathInt) Shown() bool {
+	if pi.ShowSteps <= 0 {
+		return false
+	}
+	if pi.HideSteps > 0 && pi.Steps%(pi.ShowSteps+pi.HideSteps) >= pi.ShowSteps {
+		return false
+	}
+	return !erand.BoolP32(pi.DropP, -1, &pi.Rand)
+}
+
+// AngDiff returns the difference a - b between two angles in degrees,
+// wrapped to -180..180
+func AngDiff(a, b float32) float32 {
+	d := mat32.Mod(a-b, 360)
+	if d > 180 {
+		d -= 360
+	} else if d < -180 {
+		d += 360
+	}
+	return d
+}
+
+// PathIntErr returns the error of given estimate of the position and angle
+// relative to the current true position and angle: the distance, and the
+// absolute angle difference in degrees -- for measuring the integration
+// error of a model
+func (ev *XYHDEnv) PathIntErr(pos mat32.Vec2, ang float32) (posErr, angErr float32) {
+	return pos.DistTo(ev.PosF), mat32.Abs(AngDiff(ang, float32(ev.Angle)))
+}
+
+// ConfigPathIntImpl configures the Velocity and Rotation states if the
+// PathInt mode is on, and removes them if it is off -- can be called again
+// after changing the PathInt mode
+func (ev *XYHDEnv) ConfigPathIntImpl() {
+	for _, nm := range []string{"Velocity", "Rotation"} {
+		if !ev.PathInt.On {
+			delete(ev.NextStates, nm)
+			delete(ev.CurStates, nm)
+			continue
+		}
+		st := &etensor.Float32{}
+		st.SetShape([]int{1, ev.VesSize}, nil, []string{"1", "Pop"})
+		ev.NextStates[nm] = st
+		ev.CurStates[nm] = st.Clone().(*etensor.Float32)
+	}
+}
+
+// RenderPathInt renders the noisy self-motion signals, updates the
+// dead-reckoning estimate, and withholds or degrades the true Position and
+// Angle inputs, which must already be rendered
+func (ev *XYHDEnv) RenderPathInt() {
+	pi := &ev.PathInt
+	pi.Speed = mat32.Max(ev.PosF.DistTo(ev.PrevPosF)+pi.VelNoise*float32(pi.Rand.NormFloat64(-1)), 0)
+	pi.Rot = float32(ev.RotAng) + pi.RotNoise*float32(pi.Rand.NormFloat64(-1))
+	vs := ev.NextStates["Velocity"]
+	ev.PopCode.Encode(&vs.Values, pi.Speed/pi.MaxSpeed, ev.VesSize, false)
+	rs := ev.NextStates["Rotation"]
+	ev.PopCode.Encode(&rs.Values, 0.5*(-pi.Rot/90)+0.5, ev.VesSize, false) // same as Vestibular
+
+	pi.EstAngle = mat32.Mod(pi.EstAngle+pi.Rot+360, 360)
+	a := mat32.DegToRad(pi.EstAngle)
+	pi.EstPos = pi.EstPos.Add(mat32.Vec2{mat32.Cos(a), mat32.Sin(a)}.MulScalar(pi.Speed))
+
+	shown := pi.Shown()
+	pi.PosShown = shown || !pi.DropPos
+	pi.AngShown = shown || !pi.DropAngle
+	pi.Steps++
+
+	if pi.PosShown {
+		pi.EstPos = ev.PosF
+		if pi.PosNoise > 0 {
+			nz := func() float32 { return pi.PosNoise * float32(pi.Rand.NormFloat64(-1)) }
+			ev.RenderPosition("Position", ev.PosF.Add(mat32.Vec2{nz(), nz()}))
+			ev.RenderPosition("PrevPosition", ev.PrevPosF.Add(mat32.Vec2{nz(), nz()}))
+		}
+	} else {
+		ev.NextStates["Position"].SetZeros()
+		ev.NextStates["PrevPosition"].SetZeros()
+	}
+	if pi.AngShown {
+		pi.EstAngle = float32(ev.Angle)
+		if pi.AngNoise > 0 {
+			for _, nm := range []string{"Angle", "PrevAngle"} {
+				ang := float32(ev.Angle)
+				if nm == "PrevAngle" {
+					ang = float32(ev.PrevAngle)
+				}
+				ang = mat32.Mod(ang+pi.AngNoise*float32(pi.Rand.NormFloat64(-1))+360, 360)
+				ev.AngCode.Encode(&ev.NextStates[nm].Values, ang/360, ev.RingSize)
+			}
+		}
+	} else {
+		ev.NextStates["Angle"].SetZeros()
+		ev.NextStates["PrevAngle"].SetZeros()
+	}
+}
+
+// RecordPathInt records the ground truth and dead-reckoning estimate for
+// the current step in the PathInt Log
+func (ev *XYHDEnv) RecordPathInt() {
+	pi := &ev.PathInt
+	pe, ae := ev.PathIntErr(pi.EstPos, pi.EstAngle)
+	pi.Log = append(pi.Log, PathIntRec{Tick: ev.Tick.Cur, Pos: ev.PosF, Angle: float32(ev.Angle), PosShown: pi.PosShown, AngShown: pi.AngShown, EstPos: pi.EstPos, EstAngle: pi.EstAngle, PosErr: pe, AngErr: ae})
+}
+
+// PathIntTable returns a table of the PathInt Log, one row per step, for
+// plotting the integration error over time
+func (ev *XYHDEnv) PathIntTable() *etable.Table {
+	lg := ev.PathInt.Log
+	dt := etable.NewTable("PathInt")
+	sch := etable.Schema{
+		{"Tick", etensor.INT64, nil, nil},
+		{"X", etensor.FLOAT32, nil, nil},
+		{"Y", etensor.FLOAT32, nil, nil},
+		{"Angle", etensor.FLOAT32, nil, nil},
+		{"PosShown", etensor.INT64, nil, nil},
+		{"AngShown", etensor.INT64, nil, nil},
+		{"EstX", etensor.FLOAT32, nil, nil},
+		{"EstY", etensor.FLOAT32, nil, nil},
+		{"EstAngle", etensor.FLOAT32, nil, nil},
+		{"PosErr", etensor.FLOAT32, nil, nil},
+		{"AngErr", etensor.FLOAT32, nil, nil},
+	}
+	dt.SetFromSchema(sch, len(lg))
+	b2f := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	for i, r := range lg {
+		dt.SetCellFloat("Tick", i, float64(r.Tick))
+		dt.SetCellFloat("X", i, float64(r.Pos.X))
+		dt.SetCellFloat("Y", i, float64(r.Pos.Y))
+		dt.SetCellFloat("Angle", i, float64(r.Angle))
+		dt.SetCellFloat("PosShown", i, b2f(r.PosShown))
+		dt.SetCellFloat("AngShown", i, b2f(r.AngShown))
+		dt.SetCellFloat("EstX", i, float64(r.EstPos.X))
+		dt.SetCellFloat("EstY", i, float64(r.EstPos.Y))
+		dt.SetCellFloat("EstAngle", i, float64(r.EstAngle))
+		dt.SetCellFloat("PosErr", i, float64(r.PosErr))
+		dt.SetCellFloat("AngErr", i, float64(r.AngErr))
+	}
+	return dt
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/goki/mat32"
+)
+
+// testPathInt returns a world in the path-integration mode, with the
+// schedule and noise set by given function
+func testPathInt(t *testing.T, set func(pi *PathInt)) *XYHDEnv {
+	return testEnv(t, func(ev *XYHDEnv) {
+		ev.PathInt.On = true
+		set(&ev.PathInt)
+		ev.ConfigPathIntImpl()
+	})
+}
+
+// sumState returns the sum of the values of given current state
+func sumState(ev *XYHDEnv, nm string) float32 {
+	sum := float32(0)
+	for _, v := range ev.CurStates[nm].Values {
+		sum += v
+	}
+	return sum
+}
+
+func TestPathIntSchedule(t *testing.T) {
+	ev := testPathInt(t, func(pi *PathInt) {
+		pi.ShowSteps = 2
+		pi.HideSteps = 3
+		pi.DropP = 0
+		pi.DropAngle = false
+	})
+	nstep := 50
+	for i := 0; i < nstep; i++ {
+		ev.Action(ev.Acts[ev.ActGen()], nil)
+		ev.Step()
+		rec := ev.PathInt.Log[i]
+		if rec.PosShown != (i%5 < 2) || !rec.AngShown {
+			t.Fatalf("step: %d position shown: %v angle shown: %v not on the schedule", i, rec.PosShown, rec.AngShown)
+		}
+		if pos := sumState(ev, "Position"); (pos > 0) != rec.PosShown {
+			t.Errorf("step: %d position input: %g but shown: %v", i, pos, rec.PosShown)
+		}
+		if ang := sumState(ev, "Angle"); ang == 0 {
+			t.Errorf("step: %d angle withheld without DropAngle", i)
+		}
+		if rec.PosShown && (rec.PosErr != 0 || rec.EstPos != rec.Pos) {
+			t.Errorf("step: %d estimate: %v not reset to the truth: %v when shown", i, rec.EstPos, rec.Pos)
+		}
+		if rec.Tick != ev.Tick.Cur || rec.Pos != ev.PosF {
+			t.Errorf("step: %d log: %+v does not match the current state", i, rec)
+		}
+	}
+	if dt := ev.PathIntTable(); dt.Rows != nstep {
+		t.Errorf("path int table rows: %d != steps: %d", dt.Rows, nstep)
+	}
+}
+
+func TestPathIntNoise(t *testing.T) {
+	ev := testPathInt(t, func(pi *PathInt) {
+		pi.ShowSteps = 0 // always withheld
+		pi.VelNoise = 0
+		pi.RotNoise = 0
+	})
+	runSteps(ev, 200)
+	for i, rec := range ev.PathInt.Log {
+		if rec.PosShown || rec.AngShown || rec.PosErr > 1.0e-3 || rec.AngErr > 1.0e-3 {
+			t.Fatalf("step: %d noise-free dead reckoning: %+v not exact", i, rec)
+		}
+	}
+	if sumState(ev, "Position") != 0 || sumState(ev, "Angle") != 0 {
+		t.Error("position or angle input not withheld")
+	}
+
+	ev = testPathInt(t, func(pi *PathInt) {
+		pi.ShowSteps = 0
+	})
+	runSteps(ev, 200)
+	lg := ev.PathInt.Log
+	if lg[len(lg)-1].PosErr <= lg[0].PosErr {
+		t.Errorf("noisy dead reckoning error: %g did not grow from: %g", lg[len(lg)-1].PosErr, lg[0].PosErr)
+	}
+
+	if d := AngDiff(350, 10); d != -20 {
+		t.Errorf("AngDiff(350, 10): %g != -20", d)
+	}
+	if _, ae := ev.PathIntErr(ev.PosF, float32(ev.Angle)+370); mat32.Abs(ae-10) > 1.0e-4 {
+		t.Errorf("angle error: %g != 10", ae)
+	}
+}
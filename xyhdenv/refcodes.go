@@ -0,0 +1,327 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/emer/emergent/evec"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/ki/ints"
+	"github.com/goki/mat32"
+)
+
+// RefCodes configures the optional reference spatial codes, rendered as
+// extra states for comparison with learned representations: Gaussian
+// place fields (Place), hexagonal grid-cell codes at several scales (Grid),
+// and boundary vector cells tuned to the distance of the walls in several
+// allocentric directions (BVC).  Call ConfigRefCodesImpl after changing
+// which codes are on.
+type RefCodes struct {
+
+	// render the Place state: Gaussian place fields with centers on an even grid over the interior of the world
+	Place bool `desc:"render the Place state: Gaussian place fields with centers on an even grid over the interior of the world"`
+
+	// [viewif: Place] number of place fields in each dimension
+	PlaceSize evec.Vec2i `viewif:"Place" desc:"number of place fields in each dimension"`
+
+	// [viewif: Place] standard deviation of the place fields, in world cells
+	PlaceSigma float32 `viewif:"Place" desc:"standard deviation of the place fields, in world cells"`
+
+	// render the Grid state: hexagonal grid-cell codes, with one module of GridPhases x GridPhases spatial phases for each scale
+	Grid bool `desc:"render the Grid state: hexagonal grid-cell codes, with one module of GridPhases x GridPhases spatial phases for each scale"`
+
+	// [viewif: Grid] spacing between the firing fields of each grid module, in world cells
+	GridScales []float32 `viewif:"Grid" desc:"spacing between the firing fields of each grid module, in world cells"`
+
+	// [viewif: Grid] orientation of the lattice of each grid module, in degrees -- missing values are 0
+	GridOris []float32 `viewif:"Grid" desc:"orientation of the lattice of each grid module, in degrees -- missing values are 0"`
+
+	// [viewif: Grid] number of spatial phases along each lattice axis within a grid module
+	GridPhases int `viewif:"Grid" desc:"number of spatial phases along each lattice axis within a grid module"`
+
+	// render the BVC state: boundary vector cells, for each preferred distance and allocentric direction
+	BVC bool `desc:"render the BVC state: boundary vector cells, for each preferred distance and allocentric direction"`
+
+	// [viewif: BVC] number of allocentric directions, evenly spaced starting at 0 degrees
+	BVCDirs int `viewif:"BVC" desc:"number of allocentric directions, evenly spaced starting at 0 degrees"`
+
+	// [viewif: BVC] preferred distances to a boundary, in world cells
+	BVCDists []float32 `viewif:"BVC" desc:"preferred distances to a boundary, in world cells"`
+
+	// [viewif: BVC] width of the distance tuning at a preferred distance of 0, in world cells
+	BVCSigma float32 `viewif:"BVC" desc:"width of the distance tuning at a preferred distance of 0, in world cells"`
+
+	// [viewif: BVC] increase in the width of the distance tuning per unit of preferred distance, so that cells tuned to farther boundaries are broader
+	BVCSigmaInc float32 `viewif:"BVC" desc:"increase in the width of the distance tuning per unit of preferred distance, so that cells tuned to farther boundaries are broader"`
+}
+
+// Defaults sets the default parameters for a world of given size,
+// with all codes off
+func (rc *RefCodes) Defaults(size evec.Vec2i) {
+	mx := float32(ints.MaxInt(size.X, size.Y))
+	rc.PlaceSize.Set(10, 10)
+	rc.PlaceSigma = 0.05 * mx
+	rc.GridScales = []float32{0.15 * mx, 0.21 * mx, 0.3 * mx}
+	rc.GridOris = []float32{0, 20, 40}
+	rc.GridPhases = 4
+	rc.BVCDirs = 8
+	rc.BVCDists = []float32{0.025 * mx, 0.1 * mx, 0.3 * mx}
+	rc.BVCSigma = 2
+	rc.BVCSigmaInc = 0.25
+}
+
+// ConfigRefCodesImpl configures the states for the reference codes that
+// are on, and removes those that are off -- can be called again after
+// changing the RefCodes
+func (ev *XYHDEnv) ConfigRefCodesImpl() {
+	rc := &ev.RefCodes
+	shapes := map[string][]int{}
+	names := map[string][]string{}
+	if rc.Place {
+		shapes["Place"] = []int{rc.PlaceSize.Y, rc.PlaceSize.X}
+		names["Place"] = []string{"Y", "X"}
+	}
+	if rc.Grid {
+		shapes["Grid"] = []int{len(rc.GridScales), rc.GridPhases, rc.GridPhases}
+		names["Grid"] = []string{"Scale", "Phase2", "Phase1"}
+	}
+	if rc.BVC {
+		shapes["BVC"] = []int{len(rc.BVCDists), rc.BVCDirs}
+		names["BVC"] = []string{"Dist", "Dir"}
+	}
+	for _, nm := range []string{"Place", "Grid", "BVC"} {
+		shp, ok := shapes[nm]
+		if !ok {
+			delete(ev.NextStates, nm)
+			delete(ev.CurStates, nm)
+			continue
+		}
+		st := &etensor.Float32{}
+		st.SetShape(shp, nil, names[nm])
+		ev.NextStates[nm] = st
+		ev.CurStates[nm] = st.Clone().(*etensor.Float32)
+	}
+}
+
+// RenderRefCodes renders the reference codes that are on
+func (ev *XYHDEnv) RenderRefCodes() {
+	if _, ok := ev.NextStates["Place"]; ok {
+		ev.RenderPlace()
+	}
+	if _, ok := ev.NextStates["Grid"]; ok {
+		ev.RenderGrid()
+	}
+	if _, ok := ev.NextStates["BVC"]; ok {
+		ev.RenderBVC()
+	}
+}
+
+// RenderPlace renders the Place state: a Gaussian function of the distance
+// from the current position to each place field center
+func (ev *XYHDEnv) RenderPlace() {
+	rc := &ev.RefCodes
+	ps := ev.NextStates["Place"]
+	sx := float32(ev.Size.X-2) / float32(rc.PlaceSize.X)
+	sy := float32(ev.Size.Y-2) / float32(rc.PlaceSize.Y)
+	nvar := -1 / (2 * rc.PlaceSigma * rc.PlaceSigma)
+	for y := 0; y < rc.PlaceSize.Y; y++ {
+		for x := 0; x < rc.PlaceSize.X; x++ {
+			c := mat32.Vec2{1 + (float32(x)+0.5)*sx, 1 + (float32(y)+0.5)*sy}
+			d := ev.PosF.DistTo(c)
+			ps.Set([]int{y, x}, mat32.Exp(d*d*nvar))
+		}
+	}
+}
+
+// GridAct returns the activity of a grid cell with given spacing and
+// orientation (in degrees), at given position relative to its phase:
+// the sum of three cosine gratings 60 degrees apart, normalized to 0..1,
+// which is 1 on a hexagonal lattice of firing fields
+func GridAct(spacing, ori float32, d mat32.Vec2) float32 {
+	k := 4 * mat32.Pi / (mat32.Sqrt(3) * spacing)
+	sum := float32(0)
+	for _, a := range []float32{-30, 30, 90} {
+		ar := mat32.DegToRad(ori + a)
+		sum += mat32.Cos(k * (mat32.Cos(ar)*d.X + mat32.Sin(ar)*d.Y))
+	}
+	return (sum + 1.5) / 4.5
+}
+
+// RenderGrid renders the Grid state: for each scale, grid cells with
+// phases evenly tiling the unit rhombus of the lattice
+func (ev *XYHDEnv) RenderGrid() {
+	rc := &ev.RefCodes
+	gs := ev.NextStates["Grid"]
+	np := float32(rc.GridPhases)
+	for s, sp := range rc.GridScales {
+		ori := float32(0)
+		if s < len(rc.GridOris) {
+			ori = rc.GridOris[s]
+		}
+		a1, a2 := mat32.DegToRad(ori), mat32.DegToRad(ori+60)
+		e1 := mat32.Vec2{mat32.Cos(a1), mat32.Sin(a1)}.MulScalar(sp / np)
+		e2 := mat32.Vec2{mat32.Cos(a2), mat32.Sin(a2)}.MulScalar(sp / np)
+		for p2 := 0; p2 < rc.GridPhases; p2++ {
+			for p1 := 0; p1 < rc.GridPhases; p1++ {
+				ph := e1.MulScalar(float32(p1)).Add(e2.MulScalar(float32(p2)))
+				gs.Set([]int{s, p2, p1}, GridAct(sp, ori, ev.PosF.Sub(ph)))
+			}
+		}
+	}
+}
+
+// WallDist returns the distance from given position to the nearest barrier
+// along given allocentric angle, in degrees, or the distance to the edge of
+// the world if there is no barrier
+func (ev *XYHDEnv) WallDist(pos mat32.Vec2, ang float32) float32 {
+	a := mat32.DegToRad(ang)
+	v := NormVecLine(mat32.Vec2{mat32.Cos(a), mat32.Sin(a)})
+	cp := pos
+	gp := evec.Vec2i{}
+	for {
+		cp, gp = NextVecPoint(cp, v)
+		if !ev.InWorld(gp) || ev.IsBarrier(ev.GetWorld(gp)) {
+			break
+		}
+	}
+	return cp.DistTo(pos)
+}
+
+// RenderBVC renders the BVC state: a Gaussian function of the difference
+// between the wall distance in each direction and the preferred distance,
+// with a width that grows with the preferred distance
+func (ev *XYHDEnv) RenderBVC() {
+	rc := &ev.RefCodes
+	bs := ev.NextStates["BVC"]
+	for di := 0; di < rc.BVCDirs; di++ {
+		wd := ev.WallDist(ev.PosF, float32(di)*360/float32(rc.BVCDirs))
+		for i, pd := range rc.BVCDists {
+			sig := rc.BVCSigma + rc.BVCSigmaInc*pd
+			dd := wd - pd
+			bs.Set([]int{i, di}, mat32.Exp(-dd*dd/(2*sig*sig)))
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////
+// Rate maps
+
+// RateMaps accumulates the occupancy of the agent in spatial bins over a
+// run, and the summed activity of each unit of given states in each bin,
+// from which firing-rate maps are computed, as in place-cell recordings
+type RateMaps struct {
+
+	// accumulate the maps on each Step
+	On bool `desc:"accumulate the maps on each Step"`
+
+	// size of the spatial bins, in world cells
+	Bin int `desc:"size of the spatial bins, in world cells"`
+
+	// names of the states to accumulate firing maps for
+	States []string `desc:"names of the states to accumulate firing maps for"`
+
+	// number of steps accumulated
+	Steps int `inactive:"+" desc:"number of steps accumulated"`
+
+	// [view: no-inline] number of steps in each spatial bin
+	Occupancy *etensor.Float32 `view:"no-inline" desc:"number of steps in each spatial bin"`
+
+	// [view: -] summed activity of each unit in each spatial bin, for each state, with shape: units, Y, X
+	Sums map[string]*etensor.Float32 `view:"-" desc:"summed activity of each unit in each spatial bin, for each state, with shape: units, Y, X"`
+}
+
+// Defaults sets default parameters
+func (rm *RateMaps) Defaults() {
+	rm.Bin = 10
+	rm.States = []string{"Place", "Grid", "BVC"}
+}
+
+// Init resets the accumulated maps for a world of given size
+func (rm *RateMaps) Init(size evec.Vec2i) {
+	rm.Steps = 0
+	ny, nx := (size.Y+rm.Bin-1)/rm.Bin, (size.X+rm.Bin-1)/rm.Bin
+	rm.Occupancy = etensor.NewFloat32([]int{ny, nx}, nil, []string{"Y", "X"})
+	rm.Sums = make(map[string]*etensor.Float32)
+}
+
+// Record accumulates the occupancy at given position, and the activity of
+// the given current states in its bin -- states not present are skipped
+func (rm *RateMaps) Record(pos evec.Vec2i, states map[string]*etensor.Float32) {
+	if rm.Occupancy == nil {
+		return
+	}
+	ny, nx := rm.Occupancy.Dim(0), rm.Occupancy.Dim(1)
+	by, bx := pos.Y/rm.Bin, pos.X/rm.Bin
+	if by < 0 || bx < 0 || by >= ny || bx >= nx {
+		return
+	}
+	rm.Steps++
+	rm.Occupancy.Values[by*nx+bx]++
+	for _, nm := range rm.States {
+		st, ok := states[nm]
+		if !ok {
+			continue
+		}
+		nu := st.Len()
+		sm, ok := rm.Sums[nm]
+		if !ok {
+			sm = etensor.NewFloat32([]int{nu, ny, nx}, nil, []string{"Unit", "Y", "X"})
+			rm.Sums[nm] = sm
+		}
+		for u, v := range st.Values {
+			sm.Values[(u*ny+by)*nx+bx] += v
+		}
+	}
+}
+
+// OccupancyTable returns a table with the number of steps accumulated and
+// the proportion of them in each spatial bin
+func (rm *RateMaps) OccupancyTable() *etable.Table {
+	dt := etable.NewTable("Occupancy")
+	if rm.Occupancy == nil {
+		return dt
+	}
+	sch := etable.Schema{
+		{"Steps", etensor.INT64, nil, nil},
+		{"Occupancy", etensor.FLOAT32, rm.Occupancy.Shape.Shp, rm.Occupancy.Shape.Nms},
+	}
+	dt.SetFromSchema(sch, 1)
+	dt.SetCellFloat("Steps", 0, float64(rm.Steps))
+	oc := dt.CellTensor("Occupancy", 0).(*etensor.Float32)
+	if rm.Steps > 0 {
+		for i, v := range rm.Occupancy.Values {
+			oc.Values[i] = v / float32(rm.Steps)
+		}
+	}
+	return dt
+}
+
+// FiringMapTable returns a table with the firing-rate map of each unit
+// of given state, one row per unit: the mean activity in each spatial bin,
+// which is 0 for bins that were never visited
+func (rm *RateMaps) FiringMapTable(state string) *etable.Table {
+	dt := etable.NewTable(state + "Maps")
+	sm, ok := rm.Sums[state]
+	if !ok {
+		return dt
+	}
+	nu := sm.Dim(0)
+	ny, nx := sm.Dim(1), sm.Dim(2)
+	sch := etable.Schema{
+		{"Unit", etensor.INT64, nil, nil},
+		{"Map", etensor.FLOAT32, []int{ny, nx}, []string{"Y", "X"}},
+	}
+	dt.SetFromSchema(sch, nu)
+	for u := 0; u < nu; u++ {
+		dt.SetCellFloat("Unit", u, float64(u))
+		mp := dt.CellTensor("Map", u).(*etensor.Float32)
+		for i, oc := range rm.Occupancy.Values {
+			if oc > 0 {
+				mp.Values[i] = sm.Values[u*ny*nx+i] / oc
+			}
+		}
+	}
+	return dt
+}
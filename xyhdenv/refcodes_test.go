@@ -0,0 +1,142 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/emer/etable/etensor"
+	"github.com/goki/mat32"
+)
+
+// testEnv configures a world, calls given function (if non-nil)
+// to set params, and inits it
+func testEnv(t *testing.T, set func(ev *XYHDEnv)) *XYHDEnv {
+	ev := &XYHDEnv{}
+	ev.Config(1000)
+	if set != nil {
+		set(ev)
+	}
+	ev.Init(0)
+	if err := ev.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	return ev
+}
+
+// runSteps takes n steps with ActGen actions
+func runSteps(ev *XYHDEnv, n int) {
+	for i := 0; i < n; i++ {
+		ev.Action(ev.Acts[ev.ActGen()], nil)
+		ev.Step()
+	}
+}
+
+// maxIdx returns the index of the maximum value in given tensor
+func maxIdx(st *etensor.Float32) int {
+	mi := 0
+	for i, v := range st.Values {
+		if v > st.Values[mi] {
+			mi = i
+		}
+	}
+	return mi
+}
+
+func TestRefCodes(t *testing.T) {
+	ev := testEnv(t, func(ev *XYHDEnv) {
+		ev.RefCodes.Place = true
+		ev.RefCodes.Grid = true
+		ev.RefCodes.BVC = true
+		ev.ConfigRefCodesImpl()
+	})
+	rc := &ev.RefCodes
+	runSteps(ev, 100)
+	ev.Action("Stay", nil)
+	ev.Step()
+	ps := ev.CurStates["Place"]
+	mi := maxIdx(ps)
+	sx := float32(ev.Size.X-2) / float32(rc.PlaceSize.X)
+	sy := float32(ev.Size.Y-2) / float32(rc.PlaceSize.Y)
+	c := mat32.Vec2{1 + (float32(mi%rc.PlaceSize.X)+0.5)*sx, 1 + (float32(mi/rc.PlaceSize.X)+0.5)*sy}
+	if mat32.Abs(c.X-ev.PosF.X) > sx/2 || mat32.Abs(c.Y-ev.PosF.Y) > sy/2 {
+		t.Errorf("most active place field: %v not centered near the position: %v", c, ev.PosF)
+	}
+
+	gs := ev.CurStates["Grid"]
+	if gs.Dim(0) != len(rc.GridScales) || gs.Len() != len(rc.GridScales)*rc.GridPhases*rc.GridPhases {
+		t.Errorf("grid shape: %v != scales x phases", gs.Shapes())
+	}
+	for _, ori := range []float32{0, 20} {
+		sp := float32(30)
+		a := mat32.DegToRad(ori)
+		lat := mat32.Vec2{mat32.Cos(a), mat32.Sin(a)}.MulScalar(sp)
+		if g := GridAct(sp, ori, mat32.Vec2{}); mat32.Abs(g-1) > 1.0e-5 {
+			t.Errorf("grid activity: %g != 1 at the phase", g)
+		}
+		if g := GridAct(sp, ori, lat); mat32.Abs(g-1) > 1.0e-4 {
+			t.Errorf("grid activity: %g != 1 at a lattice point: %v", g, lat)
+		}
+		if g := GridAct(sp, ori, lat.MulScalar(0.5)); g > 0.5 {
+			t.Errorf("grid activity: %g > 0.5 between lattice points", g)
+		}
+	}
+
+	ev.PosI = ev.Size.DivScalar(2)
+	ev.PosF = ev.PosI.ToVec2()
+	for _, wd := range []struct {
+		ang  float32
+		dist int
+	}{{0, ev.Size.X - 1 - ev.PosI.X}, {90, ev.Size.Y - 1 - ev.PosI.Y}, {180, ev.PosI.X}, {270, ev.PosI.Y}} {
+		if d := ev.WallDist(ev.PosF, wd.ang); mat32.Abs(d-float32(wd.dist)) > 1.0e-4 {
+			t.Errorf("wall distance at: %g: %g != %d", wd.ang, d, wd.dist)
+		}
+	}
+	ev.RenderBVC()
+	bs := ev.NextStates["BVC"]
+	far := bs.Value([]int{0, 0}) // nearest preferred distance, direction 0
+	ev.PosF.X = float32(ev.Size.X - 3)
+	ev.RenderBVC()
+	if near := bs.Value([]int{0, 0}); near <= far {
+		t.Errorf("near boundary cell activity: %g not higher next to the wall than: %g", near, far)
+	}
+}
+
+func TestRateMaps(t *testing.T) {
+	ev := testEnv(t, func(ev *XYHDEnv) {
+		ev.RefCodes.Place = true
+		ev.ConfigRefCodesImpl()
+		ev.RateMaps.On = true
+	})
+	rm := &ev.RateMaps
+	nstep := 1000
+	runSteps(ev, nstep)
+	if rm.Steps != nstep {
+		t.Errorf("rate map steps: %d != %d", rm.Steps, nstep)
+	}
+	ot := ev.RateMaps.OccupancyTable()
+	sum := float32(0)
+	for _, v := range ot.CellTensor("Occupancy", 0).(*etensor.Float32).Values {
+		sum += v
+	}
+	if mat32.Abs(sum-1) > 1.0e-4 {
+		t.Errorf("occupancy sums to: %g != 1", sum)
+	}
+	ft := ev.RateMaps.FiringMapTable("Place")
+	np := ev.RefCodes.PlaceSize.X * ev.RefCodes.PlaceSize.Y
+	if ft.Rows != np {
+		t.Fatalf("firing map rows: %d != place fields: %d", ft.Rows, np)
+	}
+	for u := 0; u < np; u++ {
+		for i, v := range ft.CellTensor("Map", u).(*etensor.Float32).Values {
+			if v < 0 || v > 1 || (v > 0 && rm.Occupancy.Values[i] == 0) {
+				t.Fatalf("unit: %d bin: %d firing rate: %g out of range", u, i, v)
+			}
+		}
+	}
+	if ft := ev.RateMaps.FiringMapTable("Grid"); ft.Rows != 0 {
+		t.Errorf("firing maps for a state that is off: %d rows", ft.Rows)
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/emer/emergent/erand"
+	"github.com/emer/emergent/evec"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/mat32"
+)
+
+// TrajGen is a random-walk trajectory generator, used by ActGen instead of
+// the default heuristic when On, similar to published models of rat
+// trajectories (e.g., Raudies & Hasselmo, 2012).  It maintains a continuous
+// heading that changes by a random turn on each step, and is steered away
+// from walls, toward walls by thigmotaxis, and toward less-visited areas
+// until the coverage target is reached.  The actions turn the agent toward
+// the heading, and the speed is realized by moving on the proportion of
+// steps given by the sampled speed, taking the Stay action otherwise.
+type TrajGen struct {
+
+	// use the trajectory generator in ActGen instead of the default heuristic
+	On bool `desc:"use the trajectory generator in ActGen instead of the default heuristic"`
+
+	// [viewif: On] distribution of the speed on each step, in world cells per step -- the agent moves at most 1 cell per step, so speeds above 1 carry over to the next steps
+	Speed erand.RndParams `viewif:"On" desc:"distribution of the speed on each step, in world cells per step -- the agent moves at most 1 cell per step, so speeds above 1 carry over to the next steps"`
+
+	// [viewif: On] distribution of the turn of the heading on each step, in degrees (positive = left)
+	Turn erand.RndParams `viewif:"On" desc:"distribution of the turn of the heading on each step, in degrees (positive = left)"`
+
+	// [viewif: On] distance to a wall ahead within which the heading is turned away from it, in world cells
+	AvoidDist float32 `viewif:"On" desc:"distance to a wall ahead within which the heading is turned away from it, in world cells"`
+
+	// [viewif: On] strength of thigmotaxis (0..1): the proportion of the angle difference per step by which the heading is pulled to run along the nearest wall when within ThigmoDist of it, and toward it otherwise
+	Thigmo float32 `viewif:"On" desc:"strength of thigmotaxis (0..1): the proportion of the angle difference per step by which the heading is pulled to run along the nearest wall when within ThigmoDist of it, and toward it otherwise"`
+
+	// [viewif: On] distance to the nearest wall within which thigmotaxis runs along the wall, in world cells
+	ThigmoDist float32 `viewif:"On" desc:"distance to the nearest wall within which thigmotaxis runs along the wall, in world cells"`
+
+	// [viewif: On] strength of the coverage bias (0..1): the proportion of the angle difference per step by which the heading is pulled toward the least-visited nearby bin, until CoverTarget is reached
+	CoverBias float32 `viewif:"On" desc:"strength of the coverage bias (0..1): the proportion of the angle difference per step by which the heading is pulled toward the least-visited nearby bin, until CoverTarget is reached"`
+
+	// target Coverage (proportion of bins visited), after which the coverage bias is off
+	CoverTarget float32 `desc:"target Coverage (proportion of bins visited), after which the coverage bias is off"`
+
+	// size of the spatial bins for the visit counts used for the coverage bias and metrics, in world cells
+	Bin int `desc:"size of the spatial bins for the visit counts used for the coverage bias and metrics, in world cells"`
+
+	// [viewif: On] seed for the random number generator, set in Init
+	RndSeed int64 `viewif:"On" desc:"seed for the random number generator, set in Init"`
+
+	// current heading, in degrees
+	Heading float32 `inactive:"+" desc:"current heading, in degrees"`
+
+	// accumulated distance to move, from the sampled speeds
+	Budget float32 `inactive:"+" desc:"accumulated distance to move, from the sampled speeds"`
+
+	// [view: no-inline] number of steps in each spatial bin, over the run
+	Visits *etensor.Float32 `view:"no-inline" desc:"number of steps in each spatial bin, over the run"`
+
+	// [view: -] mask of the Visits bins that contain at least one cell that is not a barrier
+	FreeBins []bool `view:"-" desc:"mask of the Visits bins that contain at least one cell that is not a barrier"`
+
+	// number of FreeBins
+	NFree int `inactive:"+" desc:"number of FreeBins"`
+
+	// number of FreeBins that have been visited
+	NVisited int `inactive:"+" desc:"number of FreeBins that have been visited"`
+
+	// [view: -] random number generator for the trajectories
+	Rand erand.SysRand `view:"-" desc:"random number generator for the trajectories"`
+}
+
+// Defaults sets the parameters for a simple correlated random walk,
+// with the generator off
+func (tg *TrajGen) Defaults() {
+	tg.Speed.Defaults()
+	tg.Speed.Dist = erand.Mean
+	tg.Speed.Mean = 1
+	tg.Turn.Defaults()
+	tg.Turn.Dist = erand.Gaussian
+	tg.Turn.Var = 20
+	tg.AvoidDist = 3
+	tg.Thigmo = 0
+	tg.ThigmoDist = 10
+	tg.CoverBias = 0
+	tg.CoverTarget = 0.95
+	tg.Bin = 10
+	tg.RndSeed = 1
+}
+
+// RatDefaults sets the parameters for rat-like trajectories: gamma
+// distributed speeds with a mean of 0.5 cells per step, smoother turns,
+// wall avoidance, moderate thigmotaxis and a coverage bias
+func (tg *TrajGen) RatDefaults() {
+	tg.Defaults()
+	tg.Speed.Dist = erand.Gamma
+	tg.Speed.Mean = 0
+	tg.Speed.Par = 2
+	tg.Speed.Var = 4 // rate: mean = Par / Var
+	tg.Turn.Var = 15
+	tg.AvoidDist = 5
+	tg.Thigmo = 0.05
+	tg.CoverBias = 0.1
+}
+
+// Init initializes the state for a new run in a world of given size,
+// with given starting heading
+func (tg *TrajGen) Init(size evec.Vec2i, heading float32) {
+	tg.Rand.NewRand(tg.RndSeed)
+	tg.Heading = heading
+	tg.Budget = 0
+	ny, nx := (size.Y+tg.Bin-1)/tg.Bin, (size.X+tg.Bin-1)/tg.Bin
+	tg.Visits = etensor.NewFloat32([]int{ny, nx}, nil, []string{"Y", "X"})
+}
+
+// RecordVisit records a visit to the bin at given position
+func (tg *TrajGen) RecordVisit(pos evec.Vec2i) {
+	if tg.Visits == nil {
+		return
+	}
+	by, bx := pos.Y/tg.Bin, pos.X/tg.Bin
+	if by < 0 || bx < 0 || by >= tg.Visits.Dim(0) || bx >= tg.Visits.Dim(1) {
+		return
+	}
+	bi := by*tg.Visits.Dim(1) + bx
+	if tg.Visits.Values[bi] == 0 && tg.FreeBins[bi] {
+		tg.NVisited++
+	}
+	tg.Visits.Values[bi]++
+}
+
+// UpdtFreeBins updates the FreeBins mask from the current world, and the
+// number of them visited -- called in Init and when the arena changes
+func (ev *XYHDEnv) UpdtFreeBins() {
+	tg := &ev.Traj
+	nx := tg.Visits.Dim(1)
+	tg.FreeBins = make([]bool, tg.Visits.Len())
+	tg.NFree = 0
+	for y := 0; y < ev.Size.Y; y++ {
+		for x := 0; x < ev.Size.X; x++ {
+			bi := (y/tg.Bin)*nx + x/tg.Bin
+			if !tg.FreeBins[bi] && !ev.IsBarrier(ev.GetWorld(evec.Vec2i{x, y})) {
+				tg.FreeBins[bi] = true
+				tg.NFree++
+			}
+		}
+	}
+	tg.NVisited = 0
+	for i, v := range tg.Visits.Values {
+		if tg.FreeBins[i] && v > 0 {
+			tg.NVisited++
+		}
+	}
+}
+
+// Coverage returns the proportion of the free bins (with any cells that
+// are not barriers) that have been visited in the current run
+func (ev *XYHDEnv) Coverage() float32 {
+	if ev.Traj.NFree == 0 {
+		return 0
+	}
+	return float32(ev.Traj.NVisited) / float32(ev.Traj.NFree)
+}
+
+// Uniformity returns the normalized entropy of the distribution of visits
+// over the free bins in the current run: 1 = perfectly uniform exploration,
+// 0 = all visits in one bin
+func (ev *XYHDEnv) Uniformity() float32 {
+	free, n := ev.Traj.FreeBins, ev.Traj.NFree
+	if n < 2 {
+		return 0
+	}
+	sum := float32(0)
+	for i, v := range ev.Traj.Visits.Values {
+		if free[i] {
+			sum += v
+		}
+	}
+	if sum == 0 {
+		return 0
+	}
+	ent := float32(0)
+	for i, v := range ev.Traj.Visits.Values {
+		if free[i] && v > 0 {
+			p := v / sum
+			ent -= p * mat32.Log(p)
+		}
+	}
+	return ent / mat32.Log(float32(n))
+}
+
+// TrajSteer steers the heading of the trajectory generator, away from walls
+// ahead, by thigmotaxis, and toward less-visited bins
+func (ev *XYHDEnv) TrajSteer() {
+	tg := &ev.Traj
+	dirs := make([]float32, 8)
+	dists := make([]float32, 8)
+	mi := 0
+	for i := range dirs {
+		dirs[i] = float32(i) * 45
+		dists[i] = ev.WallDist(ev.PosF, dirs[i])
+		if dists[i] < dists[mi] {
+			mi = i
+		}
+	}
+	if tg.Thigmo > 0 {
+		trg := dirs[mi] // toward the nearest wall
+		if dists[mi] <= tg.ThigmoDist {
+			trg = dirs[mi] + 90 // along the wall, whichever way is closer
+			if mat32.Abs(AngDiff(dirs[mi]-90, tg.Heading)) < mat32.Abs(AngDiff(trg, tg.Heading)) {
+				trg = dirs[mi] - 90
+			}
+		}
+		tg.Heading += tg.Thigmo * AngDiff(trg, tg.Heading)
+	}
+	if tg.CoverBias > 0 && ev.Coverage() < tg.CoverTarget {
+		nx := tg.Visits.Dim(1)
+		bi := -1
+		bv := float32(0)
+		for i, d := range dirs {
+			a := mat32.DegToRad(d)
+			p := evec.NewVec2iFmVec2Round(ev.PosF.Add(mat32.Vec2{mat32.Cos(a), mat32.Sin(a)}.MulScalar(float32(tg.Bin))))
+			if dists[i] < float32(tg.Bin) || !ev.InWorld(p) {
+				continue
+			}
+			v := tg.Visits.Values[(p.Y/tg.Bin)*nx+p.X/tg.Bin]
+			if bi < 0 || v < bv {
+				bi, bv = i, v
+			}
+		}
+		if bi >= 0 {
+			tg.Heading += tg.CoverBias * AngDiff(dirs[bi], tg.Heading)
+		}
+	}
+	if wd := ev.WallDist(ev.PosF, tg.Heading); wd < tg.AvoidDist {
+		// turn toward the more open side, more strongly when closer
+		sgn := float32(1)
+		if ev.WallDist(ev.PosF, tg.Heading-90) > ev.WallDist(ev.PosF, tg.Heading+90) {
+			sgn = -1
+		}
+		tg.Heading += sgn * 90 * (1 - wd/tg.AvoidDist)
+		if ev.WallDist(ev.PosF, tg.Heading) < 1.5 { // still blocked: most open direction
+			mx := 0
+			for i := range dists {
+				if dists[i] > dists[mx] {
+					mx = i
+				}
+			}
+			tg.Heading = dirs[mx]
+		}
+	}
+	tg.Heading = mat32.Mod(tg.Heading+360, 360)
+}
+
+// TrajAct returns the next action from the trajectory generator: Stay if
+// the accumulated speed is less than 1 cell, and otherwise the turn toward
+// the heading, or Forward if already facing it
+func (ev *XYHDEnv) TrajAct() int {
+	tg := &ev.Traj
+	tg.Heading += float32(tg.Turn.Gen(-1, &tg.Rand))
+	ev.TrajSteer()
+	tg.Budget = mat32.Min(tg.Budget+mat32.Max(float32(tg.Speed.Gen(-1, &tg.Rand)), 0), 2)
+	if tg.Budget < 1 {
+		ev.ActGenTrace("traj stay", ev.ActMap["Stay"])
+		return ev.ActMap["Stay"]
+	}
+	tg.Budget--
+	act := ev.ActMap["Forward"]
+	hd := AngDiff(tg.Heading, float32(ev.Angle))
+	switch {
+	case hd > 0.5*float32(ev.AngInc):
+		act = ev.ActMap["Left"]
+	case hd < -0.5*float32(ev.AngInc):
+		act = ev.ActMap["Right"]
+	}
+	ev.ActGenTrace("traj", act)
+	return act
+}
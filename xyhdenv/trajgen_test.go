@@ -0,0 +1,100 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/goki/mat32"
+)
+
+// testTraj returns a world with the trajectory generator on,
+// with params set by given function
+func testTraj(t *testing.T, set func(tg *TrajGen)) *XYHDEnv {
+	return testEnv(t, func(ev *XYHDEnv) {
+		ev.Traj.On = true
+		set(&ev.Traj)
+	})
+}
+
+// wallDist returns the distance to the nearest wall in the 4 cardinal directions
+func wallDist(ev *XYHDEnv) float32 {
+	md := ev.WallDist(ev.PosF, 0)
+	for _, a := range []float32{90, 180, 270} {
+		md = mat32.Min(md, ev.WallDist(ev.PosF, a))
+	}
+	return md
+}
+
+func TestCoverage(t *testing.T) {
+	ev := testTraj(t, func(tg *TrajGen) {})
+	tg := &ev.Traj
+	nb := (ev.Size.X / tg.Bin) * (ev.Size.Y / tg.Bin)
+	if tg.NFree != nb || ev.Coverage() != 0 {
+		t.Errorf("free bins: %d != %d or coverage: %g != 0 at the start", tg.NFree, nb, ev.Coverage())
+	}
+	runSteps(ev, 500)
+	nv := 0
+	for _, v := range tg.Visits.Values {
+		if v > 0 {
+			nv++
+		}
+	}
+	if cv := ev.Coverage(); nv == 0 || cv != float32(nv)/float32(nb) {
+		t.Errorf("coverage: %g != visited: %d of: %d bins", cv, nv, nb)
+	}
+	if u := ev.Uniformity(); u <= 0 || u >= 1 {
+		t.Errorf("uniformity: %g out of range", u)
+	}
+	ev.SetArena(ArenaCircle)
+	if tg.NFree >= nb || tg.NVisited > tg.NFree {
+		t.Errorf("circle free bins: %d visited: %d not fewer than the rect: %d", tg.NFree, tg.NVisited, nb)
+	}
+}
+
+func TestTrajGen(t *testing.T) {
+	nstep := 4000
+	ev := testTraj(t, func(tg *TrajGen) {
+		tg.Speed.Mean = 0.5
+	})
+	nstay := 0
+	for i := 0; i < nstep; i++ {
+		act := ev.ActGen()
+		if ev.Acts[act] == "Stay" {
+			nstay++
+		}
+		ev.Action(ev.Acts[act], nil)
+		ev.Step()
+	}
+	if ps := float32(nstay) / float32(nstep); mat32.Abs(ps-0.5) > 0.05 {
+		t.Errorf("proportion of Stay: %g != 1 - speed: 0.5", ps)
+	}
+	base := ev.Coverage()
+
+	ev = testTraj(t, func(tg *TrajGen) {
+		tg.Speed.Mean = 0.5
+		tg.CoverBias = 0.2
+	})
+	runSteps(ev, nstep)
+	if cv := ev.Coverage(); cv <= base {
+		t.Errorf("coverage: %g with coverage bias not more than: %g without", cv, base)
+	}
+
+	meanWall := func(thigmo float32) float32 {
+		ev := testTraj(t, func(tg *TrajGen) {
+			tg.Thigmo = thigmo
+		})
+		sum := float32(0)
+		for i := 0; i < nstep; i++ {
+			ev.Action(ev.Acts[ev.ActGen()], nil)
+			ev.Step()
+			sum += wallDist(ev)
+		}
+		return sum / float32(nstep)
+	}
+	if free, thig := meanWall(0), meanWall(0.3); thig >= free/2 {
+		t.Errorf("mean wall distance: %g with thigmotaxis not much less than: %g without", thig, free)
+	}
+}
@@ -0,0 +1,374 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/emer/emergent/erand"
+	"github.com/emer/emergent/evec"
+	"github.com/emer/etable/etable"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/ki/ints"
+	"github.com/goki/ki/kit"
+	"github.com/goki/mat32"
+)
+
+//go:generate stringer -type=TaskTypes
+
+// TaskTypes are the goal-directed navigation tasks, based on the Morris
+// water maze: each trial is an Episode, starting with the agent released
+// facing the wall at a random release point, and ending when it reaches
+// the platform, or at the time limit
+type TaskTypes int32
+
+const (
+	// TaskNone is open exploration, with no goals, rewards or trials
+	TaskNone TaskTypes = iota
+
+	// TaskHidden is the hidden-platform water maze: the platform is at a fixed
+	// location, which must be learned from the distal cues, and reaching it
+	// is rewarded -- every ProbeEvery trials is a probe trial
+	TaskHidden
+
+	// TaskVisible is the visible-cue variant: the egocentric bearing and
+	// distance of the platform are shown in the Cue state, and it can be
+	// moved to a random location each trial (RandPlat)
+	TaskVisible
+
+	// TaskProbe is the probe trial, with the platform removed: each trial
+	// lasts ProbeTime steps, and the quadrant occupancy shows the memory
+	// for the platform location
+	TaskProbe
+
+	TaskTypesN
+)
+
+var KiT_TaskTypes = kit.Enums.AddEnum(TaskTypesN, kit.NotBitFlag, nil)
+
+func (ev TaskTypes) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *TaskTypes) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+
+// Task has the parameters and state for the water-maze navigation tasks
+type Task struct {
+
+	// type of task -- use SetTask to change
+	Type TaskTypes `desc:"type of task -- use SetTask to change"`
+
+	// arena used for the task, set by SetTask
+	Arena Arenas `desc:"arena used for the task, set by SetTask"`
+
+	// location of the platform, in proportions of the Size of the world
+	PlatPos mat32.Vec2 `desc:"location of the platform, in proportions of the Size of the world"`
+
+	// radius of the platform, as a proportion of the Size of the world -- the agent reaches the platform within this distance of its center
+	PlatRadius float32 `desc:"radius of the platform, as a proportion of the Size of the world -- the agent reaches the platform within this distance of its center"`
+
+	// for TaskVisible, move the platform to a random location in the arena each trial
+	RandPlat bool `desc:"for TaskVisible, move the platform to a random location in the arena each trial"`
+
+	// number of release points, evenly spaced around the wall starting at 0 degrees, one of which is chosen at random each trial
+	ReleasePts int `desc:"number of release points, evenly spaced around the wall starting at 0 degrees, one of which is chosen at random each trial"`
+
+	// distance from the wall of the release points, in world cells
+	ReleaseDist float32 `desc:"distance from the wall of the release points, in world cells"`
+
+	// maximum number of steps in a trial before it ends without reaching the platform
+	TimeMax int `desc:"maximum number of steps in a trial before it ends without reaching the platform"`
+
+	// number of steps in a probe trial
+	ProbeTime int `desc:"number of steps in a probe trial"`
+
+	// for TaskHidden, every ProbeEvery trial is a probe trial with the platform removed -- 0 = none
+	ProbeEvery int `desc:"for TaskHidden, every ProbeEvery trial is a probe trial with the platform removed -- 0 = none"`
+
+	// reward for reaching the platform, rendered in the Reward state
+	RewMag float32 `desc:"reward for reaching the platform, rendered in the Reward state"`
+
+	// seed for the random number generator for release points and platform locations, set in Init
+	RndSeed int64 `desc:"seed for the random number generator for release points and platform locations, set in Init"`
+
+	// current location of the platform, in world coordinates
+	Plat mat32.Vec2 `inactive:"+" desc:"current location of the platform, in world coordinates"`
+
+	// current trial is a probe trial, with the platform removed
+	Probe bool `inactive:"+" desc:"current trial is a probe trial, with the platform removed"`
+
+	// release point of the current trial
+	Release int `inactive:"+" desc:"release point of the current trial"`
+
+	// number of steps in the current trial
+	Time int `inactive:"+" desc:"number of steps in the current trial"`
+
+	// length of the path in the current trial, in world cells
+	PathLen float32 `inactive:"+" desc:"length of the path in the current trial, in world cells"`
+
+	// number of steps in each quadrant of the arena in the current trial, with the quadrant centered on the platform first, then going left (counter-clockwise)
+	QuadSteps [4]int `inactive:"+" desc:"number of steps in each quadrant of the arena in the current trial, with the quadrant centered on the platform first, then going left (counter-clockwise)"`
+
+	// agent reached the platform in the current trial
+	Found bool `inactive:"+" desc:"agent reached the platform in the current trial"`
+
+	// current trial is done, and is ended by the next Step
+	Done bool `inactive:"+" desc:"current trial is done, and is ended by the next Step"`
+
+	// current trial has been ended by Step, and a new one is started by the next Action or Step
+	Ended bool `inactive:"+" desc:"current trial has been ended by Step, and a new one is started by the next Action or Step"`
+
+	// current reward
+	Rew float32 `inactive:"+" desc:"current reward"`
+
+	// record of each completed trial in the current run
+	Trials []TaskTrial `view:"-" desc:"record of each completed trial in the current run"`
+
+	// [view: -] random number generator for the task
+	Rand erand.SysRand `view:"-" desc:"random number generator for the task"`
+}
+
+// TaskTrial records the metrics of one trial of the task
+type TaskTrial struct {
+
+	// Episode counter value for the trial
+	Episode int `desc:"Episode counter value for the trial"`
+
+	// type of task
+	Type TaskTypes `desc:"type of task"`
+
+	// probe trial, with the platform removed
+	Probe bool `desc:"probe trial, with the platform removed"`
+
+	// release point
+	Release int `desc:"release point"`
+
+	// location of the platform, in world coordinates
+	Plat mat32.Vec2 `desc:"location of the platform, in world coordinates"`
+
+	// agent reached the platform
+	Found bool `desc:"agent reached the platform"`
+
+	// number of steps in the trial: the escape latency when Found
+	Latency int `desc:"number of steps in the trial: the escape latency when Found"`
+
+	// length of the path, in world cells
+	PathLen float32 `desc:"length of the path, in world cells"`
+
+	// proportion of steps in each quadrant, with the target quadrant centered on the platform first, then going left (counter-clockwise)
+	Quads [4]float32 `desc:"proportion of steps in each quadrant, with the target quadrant centered on the platform first, then going left (counter-clockwise)"`
+}
+
+// Defaults sets default parameters for a hidden platform in the NE
+// quadrant of a circular pool, with the task off
+func (tk *Task) Defaults() {
+	tk.Type = TaskNone
+	tk.Arena = ArenaCircle
+	tk.PlatPos.Set(0.7, 0.7)
+	tk.PlatRadius = 0.04
+	tk.ReleasePts = 4
+	tk.ReleaseDist = 2
+	tk.TimeMax = 1000
+	tk.ProbeTime = 500
+	tk.ProbeEvery = 0
+	tk.RewMag = 1
+	tk.RndSeed = 1
+}
+
+// SetTask sets the type of task, generates its Arena, and configures the
+// Reward and Cue states for it (removed for TaskNone) -- call Init after
+func (ev *XYHDEnv) SetTask(typ TaskTypes) {
+	ev.Task.Type = typ
+	if typ != TaskNone {
+		ev.SetArena(ev.Task.Arena)
+	}
+	ev.ConfigTaskImpl()
+}
+
+// ConfigTaskImpl configures the Reward and Cue states if there is a task,
+// and removes them otherwise
+func (ev *XYHDEnv) ConfigTaskImpl() {
+	shapes := map[string][]int{"Reward": {1, 1}, "Cue": {2, ev.RingSize}}
+	names := map[string][]string{"Reward": {"1", "1"}, "Cue": {"BearDist", "Pop"}}
+	for _, nm := range []string{"Reward", "Cue"} {
+		if ev.Task.Type == TaskNone {
+			delete(ev.NextStates, nm)
+			delete(ev.CurStates, nm)
+			continue
+		}
+		st := &etensor.Float32{}
+		st.SetShape(shapes[nm], nil, names[nm])
+		ev.NextStates[nm] = st
+		ev.CurStates[nm] = st.Clone().(*etensor.Float32)
+	}
+}
+
+// TaskInit initializes the task for a new run, and starts the first trial
+func (ev *XYHDEnv) TaskInit() {
+	tk := &ev.Task
+	tk.Rand.NewRand(tk.RndSeed)
+	tk.Trials = nil
+	ev.TaskNewTrial()
+}
+
+// WorldCtr returns the center of the world, in world coordinates
+func (ev *XYHDEnv) WorldCtr() mat32.Vec2 {
+	return ev.Size.ToVec2().SubScalar(1).DivScalar(2)
+}
+
+// TaskNewTrial starts a new trial: sets the platform location, and
+// releases the agent facing the wall at a random release point
+func (ev *XYHDEnv) TaskNewTrial() {
+	tk := &ev.Task
+	ntr := len(tk.Trials) + 1
+	tk.Probe = tk.Type == TaskProbe || (tk.Type == TaskHidden && tk.ProbeEvery > 0 && ntr%tk.ProbeEvery == 0)
+	sz := ev.Size.ToVec2().SubScalar(1)
+	tk.Plat = tk.PlatPos.Mul(sz)
+	if tk.Type == TaskVisible && tk.RandPlat {
+		for i := 0; i < 1000; i++ { // else stays at PlatPos
+			p := mat32.Vec2{tk.Rand.Float32(-1), tk.Rand.Float32(-1)}.Mul(sz)
+			if ev.WallDist(p, 0) > ev.TaskPlatRad() && !ev.IsBarrier(ev.GetWorld(evec.NewVec2iFmVec2Round(p))) {
+				tk.Plat = p
+				break
+			}
+		}
+	}
+	tk.Release = tk.Rand.Intn(tk.ReleasePts, -1)
+	dir := float32(tk.Release) * 360 / float32(tk.ReleasePts)
+	ctr := ev.WorldCtr()
+	a := mat32.DegToRad(dir)
+	d := mat32.Max(ev.WallDist(ctr, dir)-tk.ReleaseDist, 0)
+	ev.PosI = ev.FreeNear(evec.NewVec2iFmVec2Round(ctr.Add(mat32.Vec2{mat32.Cos(a), mat32.Sin(a)}.MulScalar(d))))
+	ev.PosF = ev.PosI.ToVec2()
+	ev.PrevPosF, ev.PrevPosI = ev.PosF, ev.PosI
+	ev.Angle = AngMod(int(mat32.Round(dir/float32(ev.AngInc))) * ev.AngInc % 360)
+	ev.PrevAngle = ev.Angle
+	ev.RotAng = 0
+	ev.ScanProx()
+	ev.PathInt.EstPos, ev.PathInt.EstAngle = ev.PosF, float32(ev.Angle) // release is not self-motion
+	ev.Traj.Heading = float32(ev.Angle)
+
+	tk.Time = 0
+	tk.PathLen = 0
+	tk.QuadSteps = [4]int{}
+	tk.Found = false
+	tk.Done = false
+	tk.Ended = false
+	tk.Rew = 0
+}
+
+// TaskNextTrial starts the next trial if the current one has Ended, as
+// signaled by Step, rendering its initial state -- returns true if so
+func (ev *XYHDEnv) TaskNextTrial() bool {
+	if ev.Task.Type == TaskNone || !ev.Task.Ended {
+		return false
+	}
+	ev.TaskNewTrial()
+	ev.Episode.Incr()
+	ev.Event.Set(-1)
+	ev.RenderState()
+	return true
+}
+
+// TaskPlatRad returns the radius of the platform in world cells
+func (ev *XYHDEnv) TaskPlatRad() float32 {
+	return ev.Task.PlatRadius * float32(ints.MinInt(ev.Size.X, ev.Size.Y))
+}
+
+// TaskQuad returns the quadrant of the arena of given position, relative
+// to the center of the world, with 0 centered on the platform and then
+// going left (counter-clockwise)
+func (ev *XYHDEnv) TaskQuad(pos mat32.Vec2) int {
+	ctr := ev.WorldCtr()
+	pd := ev.Task.Plat.Sub(ctr)
+	d := pos.Sub(ctr)
+	ang := AngDiff(mat32.RadToDeg(mat32.Atan2(d.Y, d.X)), mat32.RadToDeg(mat32.Atan2(pd.Y, pd.X)))
+	return int(mat32.Mod(ang+45+360, 360)/90) % 4
+}
+
+// TaskAct updates the task after the action has been taken: the path
+// length, quadrant occupancy and time, and whether the platform is reached
+// (not in probe trials) or the time is up, which ends the trial
+func (ev *XYHDEnv) TaskAct() {
+	tk := &ev.Task
+	tk.Time++
+	tk.PathLen += ev.PosF.DistTo(ev.PrevPosF)
+	tk.QuadSteps[ev.TaskQuad(ev.PosF)]++
+	tk.Rew = 0
+	tmax := tk.TimeMax
+	if tk.Probe {
+		tmax = tk.ProbeTime
+	} else if ev.PosF.DistTo(tk.Plat) <= ev.TaskPlatRad() {
+		tk.Found = true
+		tk.Rew = tk.RewMag
+	}
+	tk.Done = tk.Found || tk.Time >= tmax
+}
+
+// TaskEndTrial records the metrics of the current trial in Trials,
+// and marks it as Ended
+func (ev *XYHDEnv) TaskEndTrial() {
+	tk := &ev.Task
+	tk.Ended = true
+	tr := TaskTrial{Episode: ev.Episode.Cur, Type: tk.Type, Probe: tk.Probe, Release: tk.Release, Plat: tk.Plat, Found: tk.Found, Latency: tk.Time, PathLen: tk.PathLen}
+	for i, n := range tk.QuadSteps {
+		if tk.Time > 0 {
+			tr.Quads[i] = float32(n) / float32(tk.Time)
+		}
+	}
+	tk.Trials = append(tk.Trials, tr)
+}
+
+// RenderTask renders the Reward state, and the egocentric bearing and
+// distance of the platform in the Cue state for TaskVisible
+func (ev *XYHDEnv) RenderTask() {
+	tk := &ev.Task
+	ev.NextStates["Reward"].Values[0] = tk.Rew
+	cs := ev.NextStates["Cue"]
+	cs.SetZeros()
+	if tk.Type != TaskVisible {
+		return
+	}
+	d := tk.Plat.Sub(ev.PosF)
+	bear := mat32.Mod(mat32.RadToDeg(mat32.Atan2(d.Y, d.X))-float32(ev.Angle)+720, 360)
+	sv := cs.SubSpace([]int{0}).(*etensor.Float32)
+	ev.AngCode.Encode(&sv.Values, bear/360, ev.RingSize)
+	maxd := ev.Size.ToVec2().Length()
+	sv = cs.SubSpace([]int{1}).(*etensor.Float32)
+	ev.PopCode.Encode(&sv.Values, d.Length()/maxd, ev.RingSize, false)
+}
+
+// TaskTable returns a table of the metrics of each completed trial in the
+// current run: latency, path length and quadrant occupancy
+func (ev *XYHDEnv) TaskTable() *etable.Table {
+	trs := ev.Task.Trials
+	dt := etable.NewTable("Task")
+	sch := etable.Schema{
+		{"Episode", etensor.INT64, nil, nil},
+		{"Type", etensor.STRING, nil, nil},
+		{"Probe", etensor.INT64, nil, nil},
+		{"Release", etensor.INT64, nil, nil},
+		{"Found", etensor.INT64, nil, nil},
+		{"Latency", etensor.INT64, nil, nil},
+		{"PathLen", etensor.FLOAT32, nil, nil},
+		{"TargQuad", etensor.FLOAT32, nil, nil},
+		{"Quads", etensor.FLOAT32, []int{4}, []string{"Quad"}},
+	}
+	dt.SetFromSchema(sch, len(trs))
+	b2f := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	for i, tr := range trs {
+		dt.SetCellFloat("Episode", i, float64(tr.Episode))
+		dt.SetCellString("Type", i, tr.Type.String())
+		dt.SetCellFloat("Probe", i, b2f(tr.Probe))
+		dt.SetCellFloat("Release", i, float64(tr.Release))
+		dt.SetCellFloat("Found", i, b2f(tr.Found))
+		dt.SetCellFloat("Latency", i, float64(tr.Latency))
+		dt.SetCellFloat("PathLen", i, float64(tr.PathLen))
+		dt.SetCellFloat("TargQuad", i, float64(tr.Quads[0]))
+		qs := dt.CellTensor("Quads", i).(*etensor.Float32)
+		copy(qs.Values, tr.Quads[:])
+	}
+	return dt
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2020, The Emergent Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/emer/emergent/evec"
+	"github.com/emer/etable/etensor"
+	"github.com/goki/mat32"
+)
+
+// testTask configures and inits a world with given task
+func testTask(t *testing.T, typ TaskTypes) *XYHDEnv {
+	return testEnv(t, func(ev *XYHDEnv) {
+		ev.SetTask(typ)
+	})
+}
+
+func TestTaskReward(t *testing.T) {
+	ev := testTask(t, TaskHidden)
+	tk := &ev.Task
+	pf, pi := NextVecPoint(ev.PosF, AngVec(ev.Angle))
+	if ev.IsBarrier(ev.GetWorld(pi)) {
+		t.Fatalf("release point: %v is facing a barrier", ev.PosI)
+	}
+	tk.Plat = pf // platform one step ahead, just covering that cell
+	tk.PlatRadius = 0.5 / float32(ev.Size.X)
+	for i := 0; i < 3; i++ { // some steps away from the platform first
+		ev.Action("Stay", nil)
+		if !ev.Step() {
+			t.Fatalf("trial ended at step: %d before reaching the platform", i)
+		}
+		if rew := ev.State("Reward").(*etensor.Float32).Values[0]; rew != 0 {
+			t.Errorf("reward: %g before reaching the platform", rew)
+		}
+	}
+	ev.Action("Forward", nil)
+	if ev.Step() {
+		t.Fatal("Step did not signal the trial boundary on reaching the platform")
+	}
+	if rew := ev.State("Reward").(*etensor.Float32).Values[0]; rew != tk.RewMag {
+		t.Errorf("reward: %g != RewMag: %g on reaching the platform", rew, tk.RewMag)
+	}
+	if len(tk.Trials) != 1 || !tk.Trials[0].Found || tk.Trials[0].Latency != 4 {
+		t.Errorf("trial record: %+v not found with latency 4", tk.Trials)
+	}
+
+	ep := ev.Episode.Cur
+	ev.Action("Stay", nil)
+	if !ev.Step() {
+		t.Error("new trial ended on its first step")
+	}
+	if ev.Episode.Cur != ep+1 {
+		t.Errorf("episode: %d not incremented from: %d by the next trial", ev.Episode.Cur, ep)
+	}
+	if tk.Done || tk.Time != 1 {
+		t.Errorf("new trial: done: %v time: %d != 1", tk.Done, tk.Time)
+	}
+	if rew := ev.State("Reward").(*etensor.Float32).Values[0]; rew != 0 {
+		t.Errorf("reward: %g carried over to the new trial", rew)
+	}
+}
+
+func TestTaskTimeMax(t *testing.T) {
+	ev := testTask(t, TaskHidden)
+	tk := &ev.Task
+	tk.TimeMax = 5
+	n := 0
+	for {
+		ev.Action("Stay", nil)
+		if !ev.Step() {
+			break
+		}
+		n++
+	}
+	if n != tk.TimeMax-1 {
+		t.Errorf("trial ended after: %d steps != TimeMax: %d", n+1, tk.TimeMax)
+	}
+	if len(tk.Trials) != 1 || tk.Trials[0].Found {
+		t.Errorf("trial record: %+v not a failed trial", tk.Trials)
+	}
+}
+
+func TestTaskQuad(t *testing.T) {
+	ev := testTask(t, TaskHidden)
+	tk := &ev.Task
+	ctr := ev.WorldCtr()
+	pd := tk.Plat.Sub(ctr)
+	left := mat32.Vec2{-pd.Y, pd.X} // 90 degrees counter-clockwise
+	for q, p := range []mat32.Vec2{tk.Plat, ctr.Add(left), ctr.Sub(pd), ctr.Sub(left)} {
+		if tq := ev.TaskQuad(p); tq != q {
+			t.Errorf("quadrant: %d != %d for: %v", tq, q, p)
+		}
+	}
+}
+
+func TestTaskProbe(t *testing.T) {
+	ev := testTask(t, TaskHidden)
+	tk := &ev.Task
+	tk.ProbeEvery = 2
+	tk.ProbeTime = 20
+	tk.TimeMax = 30
+	for len(tk.Trials) < 4 {
+		ev.Action(ev.Acts[ev.ActGen()], nil)
+		ev.Step()
+	}
+	for i, tr := range tk.Trials {
+		probe := (i+1)%2 == 0
+		if tr.Probe != probe || (probe && (tr.Found || tr.Latency != tk.ProbeTime)) {
+			t.Errorf("trial: %d record: %+v not a probe: %v of time: %d", i, tr, probe, tk.ProbeTime)
+		}
+		if tr.Episode != i {
+			t.Errorf("trial: %d episode: %d", i, tr.Episode)
+		}
+		if tr.Release < 0 || tr.Release >= tk.ReleasePts || tr.PathLen > float32(tr.Latency) {
+			t.Errorf("trial: %d release: %d or path length: %g out of range", i, tr.Release, tr.PathLen)
+		}
+		sum := float32(0)
+		for _, q := range tr.Quads {
+			sum += q
+		}
+		if mat32.Abs(sum-1) > 1.0e-5 {
+			t.Errorf("trial: %d quadrant occupancy sums to: %g", i, sum)
+		}
+	}
+	if dt := ev.TaskTable(); dt.Rows != len(tk.Trials) {
+		t.Errorf("task table rows: %d != trials: %d", dt.Rows, len(tk.Trials))
+	}
+}
+
+func TestTaskRelease(t *testing.T) {
+	ev := testTask(t, TaskVisible)
+	tk := &ev.Task
+	tk.RandPlat = true
+	tk.TimeMax = 5
+	rels := map[int]bool{}
+	for n := 0; n < 20; {
+		if ev.TaskNextTrial() || n == 0 && tk.Time == 0 { // check the release state of each new trial
+			dir := tk.Release * 360 / tk.ReleasePts
+			if ev.Angle != dir || ev.WallDist(ev.PosF, float32(ev.Angle)) > tk.ReleaseDist+1 {
+				t.Errorf("release: %d angle: %d at: %v not facing the wall at: %d", tk.Release, ev.Angle, ev.PosF, dir)
+			}
+			if ev.IsBarrier(ev.GetWorld(evec.NewVec2iFmVec2Round(tk.Plat))) {
+				t.Errorf("random platform: %v in a barrier", tk.Plat)
+			}
+			rels[tk.Release] = true
+		}
+		ev.Action("Stay", nil)
+		if !ev.Step() {
+			n++
+		}
+		cue := ev.State("Cue").(*etensor.Float32)
+		sum := float32(0)
+		for _, v := range cue.Values {
+			sum += v
+		}
+		if sum == 0 {
+			t.Fatal("no platform cue for the visible task")
+		}
+	}
+	if len(rels) != tk.ReleasePts {
+		t.Errorf("release points used: %v not all: %d", rels, tk.ReleasePts)
+	}
+}
@@ -40,6 +40,12 @@ type XYHDEnv struct {
 	// size of 2D world
 	Size evec.Vec2i `desc:"size of 2D world"`
 
+	// arena preset generated by GenWorld -- use SetArena to change during a run
+	Arena Arenas `desc:"arena preset generated by GenWorld -- use SetArena to change during a run"`
+
+	// [view: inline] parameters for the arena geometry
+	ArenaParams ArenaParams `view:"inline" desc:"parameters for the arena geometry"`
+
 	// size of patterns for mats, acts
 	PatSize evec.Vec2i `desc:"size of patterns for mats, acts"`
 
@@ -61,8 +67,8 @@ type XYHDEnv struct {
 	// patterns for each material (must include Empty) and for each action
 	Pats map[string]*etensor.Float32 `desc:"patterns for each material (must include Empty) and for each action"`
 
-	// list of actions: starts with: Left, Right, Forward
-	Acts []string `desc:"list of actions: starts with: Left, Right, Forward"`
+	// list of actions: starts with: Left, Right, Forward, Stay
+	Acts []string `desc:"list of actions: starts with: Left, Right, Forward, Stay"`
 
 	// action map of action names to indexes
 	ActMap map[string]int `desc:"action map of action names to indexes"`
@@ -124,6 +130,21 @@ type XYHDEnv struct {
 	// coordinates for proximal grid points: front, right, left, back
 	ProxPos []evec.Vec2i `desc:"coordinates for proximal grid points: front, right, left, back"`
 
+	// [view: inline] optional reference spatial codes: place, grid and boundary vector cells
+	RefCodes RefCodes `view:"inline" desc:"optional reference spatial codes: place, grid and boundary vector cells"`
+
+	// occupancy and firing-rate maps accumulated over a run
+	RateMaps RateMaps `desc:"occupancy and firing-rate maps accumulated over a run"`
+
+	// [view: inline] path-integration mode, withholding or degrading the true Position and Angle, with noisy self-motion signals
+	PathInt PathInt `view:"inline" desc:"path-integration mode, withholding or degrading the true Position and Angle, with noisy self-motion signals"`
+
+	// [view: inline] random-walk trajectory generator, used by ActGen when On, and visit counts for coverage metrics
+	Traj TrajGen `view:"inline" desc:"random-walk trajectory generator, used by ActGen when On, and visit counts for coverage metrics"`
+
+	// goal-directed navigation task, based on the Morris water maze -- use SetTask to change
+	Task Task `desc:"goal-directed navigation task, based on the Morris water maze -- use SetTask to change"`
+
 	// current rendered state tensors -- extensible map
 	CurStates map[string]*etensor.Float32 `desc:"current rendered state tensors -- extensible map"`
 
@@ -169,7 +190,7 @@ func (ev *XYHDEnv) Config(ntrls int) {
 	ev.Dsc = "Example world with xy coordinate system and head direction"
 	ev.Mats = []string{"Empty", "Wall"}
 	ev.BarrierIdx = 1
-	ev.Acts = []string{"Left", "Right", "Forward"}
+	ev.Acts = []string{"Left", "Right", "Forward", "Stay"}
 	ev.Params = make(map[string]float32)
 
 	ev.Disp = false
@@ -177,6 +198,8 @@ func (ev *XYHDEnv) Config(ntrls int) {
 	ev.PatSize.Set(5, 5)
 	ev.PosSize.Set(12, 12)
 	ev.AngInc = 90
+	ev.Arena = ArenaRect
+	ev.ArenaParams.Defaults()
 	ev.RingSize = 16 // was 16
 	ev.VesSize = 12  // was 12
 	ev.PopCode.Defaults()
@@ -186,6 +209,11 @@ func (ev *XYHDEnv) Config(ntrls int) {
 	//ev.PopCode2d.SetRange(0, 1, 0.1) // assume it's a square, 2 is length of walls
 	ev.AngCode.Defaults()
 	ev.AngCode.SetRange(0, 1, 0.1) // zycyc experiment
+	ev.RefCodes.Defaults(ev.Size)
+	ev.RateMaps.Defaults()
+	ev.PathInt.Defaults()
+	ev.Traj.Defaults()
+	ev.Task.Defaults()
 
 	// debugging options:
 	ev.TraceActGen = false
@@ -259,6 +287,9 @@ func (ev *XYHDEnv) ConfigImpl() {
 	ev.NextStates["Action"] = av
 
 	ev.CopyNextToCur() // get CurStates from NextStates
+	ev.ConfigRefCodesImpl()
+	ev.ConfigPathIntImpl()
+	ev.ConfigTaskImpl()
 
 	ev.MatMap = make(map[string]int, len(ev.Mats))
 	for i, m := range ev.Mats {
@@ -313,7 +344,7 @@ func (ev *XYHDEnv) Init(run int) {
 	ev.Tick.Cur = -1
 	ev.Event.Cur = -1
 
-	ev.PosI = ev.Size.DivScalar(2) // start in middle -- could be random..
+	ev.PosI = ev.ArenaStart() // start in middle -- could be random..
 	ev.PosF = ev.PosI.ToVec2()
 	for i := 0; i < 4; i++ {
 		ev.ProxMats[i] = 0
@@ -324,6 +355,13 @@ func (ev *XYHDEnv) Init(run int) {
 
 	ev.RefreshEvents = make(map[int]*WEvent)
 	ev.AllEvents = make(map[int]*WEvent)
+	ev.RateMaps.Init(ev.Size)
+	ev.PathInt.Init(ev.PosF, float32(ev.Angle))
+	ev.Traj.Init(ev.Size, float32(ev.Angle))
+	ev.UpdtFreeBins()
+	if ev.Task.Type != TaskNone {
+		ev.TaskInit()
+	}
 }
 
 // SetWorld sets given mat at given point coord in world
@@ -336,6 +374,16 @@ func (ev *XYHDEnv) GetWorld(p evec.Vec2i) int {
 	return ev.World.Value([]int{p.Y, p.X})
 }
 
+// InWorld returns true if given point is within the world
+func (ev *XYHDEnv) InWorld(p evec.Vec2i) bool {
+	return p.X >= 0 && p.X < ev.Size.X && p.Y >= 0 && p.Y < ev.Size.Y
+}
+
+// IsBarrier returns true if given material blocks movement
+func (ev *XYHDEnv) IsBarrier(mat int) bool {
+	return mat > 0 && mat <= ev.BarrierIdx
+}
+
 ////////////////////////////////////////////////////////////////////
 // I/O
 
@@ -533,17 +581,18 @@ func (ev *XYHDEnv) TakeAct(act int) {
 	ev.PrevPosF, ev.PrevPosI = ev.PosF, ev.PosI
 	ev.PrevAngle = ev.Angle
 	switch as {
-	//case "Stay":
-	case "Left":
+	case "Stay":
+	case "Left", "Right":
 		ev.RotAng = ev.AngInc
+		if as == "Right" {
+			ev.RotAng = -ev.AngInc
+		}
 		ev.Angle = AngMod(ev.Angle + ev.RotAng)
-		ev.PosF, ev.PosI = NextVecPoint(ev.PosF, AngVec(ev.Angle)) // when L/R contains forward
-	case "Right":
-		ev.RotAng = -ev.AngInc
-		ev.Angle = AngMod(ev.Angle + ev.RotAng)
-		ev.PosF, ev.PosI = NextVecPoint(ev.PosF, AngVec(ev.Angle)) // when L/R contains forward
+		if pf, pi := NextVecPoint(ev.PosF, AngVec(ev.Angle)); !ev.IsBarrier(ev.GetWorld(pi)) {
+			ev.PosF, ev.PosI = pf, pi // when L/R contains forward
+		}
 	case "Forward":
-		if frmat > 0 && frmat <= ev.BarrierIdx {
+		if ev.IsBarrier(frmat) {
 		} else {
 			ev.PosF, ev.PosI = NextVecPoint(ev.PosF, AngVec(ev.Angle))
 		}
@@ -554,6 +603,9 @@ func (ev *XYHDEnv) TakeAct(act int) {
 		//	}
 	}
 	ev.ScanProx()
+	if ev.Task.Type != TaskNone {
+		ev.TaskAct()
+	}
 
 	ev.RenderState()
 }
@@ -637,6 +689,13 @@ func (ev *XYHDEnv) RenderState() {
 	ev.RenderPosition("Position", ev.PosF)
 	ev.RenderPosition("PrevPosition", ev.PrevPosF)
 	ev.RenderAction()
+	ev.RenderRefCodes()
+	if ev.PathInt.On {
+		ev.RenderPathInt()
+	}
+	if ev.Task.Type != TaskNone {
+		ev.RenderTask()
+	}
 }
 
 // CopyNextToCur copy next state to current state
@@ -652,15 +711,30 @@ func (ev *XYHDEnv) CopyNextToCur() {
 	}
 }
 
-// Step is called to advance the environment state
+// Step is called to advance the environment state.  If a Task trial ended
+// on the last action, Step returns false to signal the trial boundary,
+// leaving the final state of the trial (including its Reward) in CurStates,
+// and the next trial is started by the next Action or Step.
 func (ev *XYHDEnv) Step() bool {
 	ev.Epoch.Same() // good idea to just reset all non-inner-most counters at start
+	ev.TaskNextTrial()
 	ev.CopyNextToCur()
+	if ev.RateMaps.On {
+		ev.RateMaps.Record(ev.PosI, ev.CurStates)
+	}
 	ev.Tick.Incr()
+	ev.Traj.RecordVisit(ev.PosI)
+	if ev.PathInt.On {
+		ev.RecordPathInt()
+	}
 	ev.Event.Incr()
 	if ev.Trial.Incr() { // true if wraps around Max back to 0
 		ev.Epoch.Incr()
 	}
+	if ev.Task.Type != TaskNone && ev.Task.Done { // new task trial starts on next action
+		ev.TaskEndTrial()
+		return false
+	}
 	return true
 }
 
@@ -670,6 +744,7 @@ func (ev *XYHDEnv) Action(action string, nop etensor.Tensor) {
 		fmt.Printf("Action not recognized: %s\n", action)
 		return
 	}
+	ev.TaskNextTrial()
 	ev.Act = a
 	ev.TakeAct(ev.Act)
 }
@@ -719,6 +794,22 @@ var XYHDEnvProps = ki.Props{
 				}},
 			},
 		}},
+		{"SetArena", ki.Props{
+			"label": "Set Arena...",
+			"icon":  "update",
+			"desc":  "Generate the world for given arena preset",
+			"Args": ki.PropSlice{
+				{"Arena", ki.Props{}},
+			},
+		}},
+		{"SetTask", ki.Props{
+			"label": "Set Task...",
+			"icon":  "update",
+			"desc":  "Set the navigation task -- Init to start it",
+			"Args": ki.PropSlice{
+				{"Task", ki.Props{}},
+			},
+		}},
 		{"OpenPats", ki.Props{
 			"label": "Open Pats...",
 			"icon":  "file-open",
@@ -815,24 +906,17 @@ func (ev *XYHDEnv) WorldRect(st, ed evec.Vec2i, mat int) {
 	ev.WorldLineVert(evec.Vec2i{ed.X, st.Y}, evec.Vec2i{ed.X, ed.Y}, mat)
 }
 
-// GenWorld generates a world -- edit to create in way desired
+// GenWorld generates a world for the current Arena -- edit to create in way desired
 func (ev *XYHDEnv) GenWorld() {
 	wall := ev.MatMap["Wall"]
-	ev.World.SetZeros()
 	// always start with a wall around the entire world -- no seeing the turtles..
-	ev.WorldRect(evec.Vec2i{0, 0}, evec.Vec2i{ev.Size.X - 1, ev.Size.Y - 1}, wall)
+	ev.WorldFillRect(evec.Vec2i{0, 0}, evec.Vec2i{ev.Size.X - 1, ev.Size.Y - 1}, wall)
+	ev.GenArena()
 	//ev.WorldRect(evec.Vec2i{20, 20}, evec.Vec2i{40, 40}, wall)
 	//ev.WorldRect(evec.Vec2i{60, 60}, evec.Vec2i{80, 80}, wall)
 	//
 	//ev.WorldLine(evec.Vec2i{60, 20}, evec.Vec2i{80, 40}, wall) // double-thick lines = no leak
 	//ev.WorldLine(evec.Vec2i{60, 19}, evec.Vec2i{80, 39}, wall)
-
-	// don't put anything in center starting point
-	ctr := ev.Size.DivScalar(2)
-	ev.SetWorld(ctr, wall)
-
-	// clear center
-	ev.SetWorld(ctr, 0)
 }
 
 ////////////////////////////////////////////////////////////////////
@@ -848,7 +932,11 @@ func (ev *XYHDEnv) ActGenTrace(desc string, act int) {
 
 // ActGen generates an action for current situation based on simple
 // coded heuristics -- i.e., what subcortical evolutionary instincts provide.
+// If the Traj generator is On, it is used instead.
 func (ev *XYHDEnv) ActGen() int {
+	if ev.Traj.On {
+		return ev.TrajAct()
+	}
 	wall := ev.MatMap["Wall"]
 	left := ev.ActMap["Left"]
 	right := ev.ActMap["Right"]